@@ -0,0 +1,103 @@
+package assembler
+
+import "math"
+
+// Float32toFloat16 converts to IEEE 754 half precision rounding to nearest even
+func Float32toFloat16(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23) & 0xff
+	mant := b & 0x7fffff
+	if exp == 0xff {
+		if mant != 0 { // quiet NaN keeping top payload bits
+			return sign | 0x7e00 | uint16(mant>>13)
+		}
+		return sign | 0x7c00
+	}
+	e := exp - 127 + 15
+	if e >= 0x1f { // overflow
+		return sign | 0x7c00
+	}
+	if e <= 0 { // subnormal half
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - e)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		mid := uint32(1) << (shift - 1)
+		if rem > mid || (rem == mid && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+	half := uint32(e)<<10 | mant>>13
+	rem := mant & 0x1fff
+	// carry may propagate into exponent up to infinity which is correct rounding
+	if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// Float16toFloat32 converts IEEE 754 half precision to float32 exactly
+func Float16toFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// normalize subnormal
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// Float32toBFloat16 converts to bfloat16 rounding to nearest even
+func Float32toBFloat16(f float32) uint16 {
+	b := math.Float32bits(f)
+	if b&0x7fffffff > 0x7f800000 { // keep NaN quiet, rounding could turn it into infinity
+		return uint16(b>>16) | 0x40
+	}
+	return uint16((b + 0x7fff + (b>>16)&1) >> 16)
+}
+
+// BFloat16toFloat32 converts bfloat16 to float32 exactly
+func BFloat16toFloat32(h uint16) float32 {
+	return math.Float32frombits(uint32(h) << 16)
+}
+
+func f16tof32(dst []float32, src []uint16) {
+	for i, h := range src {
+		dst[i] = Float16toFloat32(h)
+	}
+}
+
+func f32tof16(dst []uint16, src []float32) {
+	for i, f := range src {
+		dst[i] = Float32toFloat16(f)
+	}
+}
+
+func bf16tof32(dst []float32, src []uint16) {
+	for i, h := range src {
+		dst[i] = BFloat16toFloat32(h)
+	}
+}
+
+func f32tobf16(dst []uint16, src []float32) {
+	for i, f := range src {
+		dst[i] = Float32toBFloat16(f)
+	}
+}
@@ -0,0 +1,67 @@
+//+build amd64,!noasm
+
+package assembler
+
+import "log"
+
+var F16toF32, BF16toF32 func(dst []float32, src []uint16)
+var F32toF16, F32toBF16 func(dst []uint16, src []float32)
+
+func init() {
+	F16toF32, F32toF16 = f16tof32, f32tof16
+	// F16C instructions use AVX registers so operating system must support AVX too
+	if useAVX && hasF16C() {
+		F16toF32, F32toF16 = F16toF32F16C, F32toF16F16C
+		if logging {
+			log.Print("Using F16C half precision conversion")
+		}
+	}
+	BF16toF32, F32toBF16 = BF16toF32SSE, f32tobf16
+	if useSSE4 {
+		F32toBF16 = F32toBF16SSE4
+		if logging {
+			log.Print("Using F32toBF16SSE4")
+		}
+	}
+}
+
+// hasF16C reports if processor has half precision conversion instructions
+func hasF16C() bool
+
+// assembler functions convert len(src)/8*8 elements, the rest is converted in Go
+
+func f16tof32F16C(dst []float32, src []uint16)
+
+func f32tof16F16C(dst []uint16, src []float32)
+
+func bf16tof32SSE(dst []float32, src []uint16)
+
+func f32tobf16SSE4(dst []uint16, src []float32)
+
+func F16toF32F16C(dst []float32, src []uint16) {
+	dst = dst[:len(src)]
+	n := len(src) &^ 7
+	f16tof32F16C(dst[:n], src[:n])
+	f16tof32(dst[n:], src[n:])
+}
+
+func F32toF16F16C(dst []uint16, src []float32) {
+	dst = dst[:len(src)]
+	n := len(src) &^ 7
+	f32tof16F16C(dst[:n], src[:n])
+	f32tof16(dst[n:], src[n:])
+}
+
+func BF16toF32SSE(dst []float32, src []uint16) {
+	dst = dst[:len(src)]
+	n := len(src) &^ 7
+	bf16tof32SSE(dst[:n], src[:n])
+	bf16tof32(dst[n:], src[n:])
+}
+
+func F32toBF16SSE4(dst []uint16, src []float32) {
+	dst = dst[:len(src)]
+	n := len(src) &^ 7
+	f32tobf16SSE4(dst[:n], src[:n])
+	f32tobf16(dst[n:], src[n:])
+}
@@ -0,0 +1,132 @@
+//+build amd64,!noasm
+
+#include "textflag.h"
+
+//func hasF16C() bool
+TEXT ·hasF16C(SB), NOSPLIT, $0-1
+	MOVL	$1, AX
+	XORL	CX, CX
+	CPUID
+	SHRL	$29, CX // bit 29 of ECX of leaf 1 indicates F16C support
+	ANDL	$1, CX
+	MOVB	CX, ret+0(FP)
+	RET
+
+// all functions convert blocks of 8 values, len(src) must be multiple of 8
+
+//func f16tof32F16C(dst []float32, src []uint16)
+TEXT ·f16tof32F16C(SB), NOSPLIT, $0
+	MOVQ	dst_base+0(FP), DI
+	MOVQ	src_base+24(FP), SI
+	MOVQ	src_len+32(FP), CX
+	SHRQ	$3, CX
+	JE		f16_end
+	f16_loop:
+		VCVTPH2PS	(SI), Y0
+		VMOVUPS		Y0, (DI)
+		ADDQ	$16, SI
+		ADDQ	$32, DI
+		DECQ	CX
+		JNE		f16_loop
+	VZEROUPPER
+f16_end:
+	RET
+
+//func f32tof16F16C(dst []uint16, src []float32)
+TEXT ·f32tof16F16C(SB), NOSPLIT, $0
+	MOVQ	dst_base+0(FP), DI
+	MOVQ	src_base+24(FP), SI
+	MOVQ	src_len+32(FP), CX
+	SHRQ	$3, CX
+	JE		f32_end
+	f32_loop:
+		VMOVUPS		(SI), Y0
+		// round to nearest even
+		VCVTPS2PH	$0, Y0, (DI)
+		ADDQ	$32, SI
+		ADDQ	$16, DI
+		DECQ	CX
+		JNE		f32_loop
+	VZEROUPPER
+f32_end:
+	RET
+
+//func bf16tof32SSE(dst []float32, src []uint16)
+TEXT ·bf16tof32SSE(SB), NOSPLIT, $0
+	MOVQ	dst_base+0(FP), DI
+	MOVQ	src_base+24(FP), SI
+	MOVQ	src_len+32(FP), CX
+	SHRQ	$3, CX
+	JE		bf16_end
+	PXOR	X7, X7
+	bf16_loop:
+		MOVOU	(SI), X0
+		// interleave zero low halves with bfloat16 high halves
+		MOVO	X7, X1
+		MOVO	X7, X2
+		PUNPCKLWL	X0, X1
+		PUNPCKHWL	X0, X2
+		MOVOU	X1, (DI)
+		MOVOU	X2, 16(DI)
+		ADDQ	$16, SI
+		ADDQ	$32, DI
+		DECQ	CX
+		JNE		bf16_loop
+bf16_end:
+	RET
+
+// ROUND converts 4 floats of x into bfloat16 in low halves of dwords,
+// X4 = 0x40, X5 = 0x7fff, X6 = 1, X8 = 0x7fffffff, X9 = 0x7f800000
+#define ROUND(x, t1, t2, t3) \
+	MOVO	x, t1; \
+	PSRLL	$16, t1; \
+	MOVO	t1, t2; \
+	PAND	X6, t2; \
+	PADDL	X5, t2; \
+	PADDL	x, t2; \
+	PSRLL	$16, t2; \
+	POR		X4, t1; \
+	MOVO	x, t3; \
+	PAND	X8, t3; \
+	PCMPGTL	X9, t3; \
+	PAND	t3, t1; \
+	PANDN	t2, t3; \
+	POR		t1, t3; \
+	MOVO	t3, x
+
+//func f32tobf16SSE4(dst []uint16, src []float32)
+TEXT ·f32tobf16SSE4(SB), NOSPLIT, $0
+	MOVQ	dst_base+0(FP), DI
+	MOVQ	src_base+24(FP), SI
+	MOVQ	src_len+32(FP), CX
+	SHRQ	$3, CX
+	JE		bf16r_end
+	MOVL	$0x40, AX
+	MOVQ	AX, X4
+	PSHUFD	$0, X4, X4
+	MOVL	$0x7fff, AX
+	MOVQ	AX, X5
+	PSHUFD	$0, X5, X5
+	MOVL	$1, AX
+	MOVQ	AX, X6
+	PSHUFD	$0, X6, X6
+	MOVL	$0x7fffffff, AX
+	MOVQ	AX, X8
+	PSHUFD	$0, X8, X8
+	MOVL	$0x7f800000, AX
+	MOVQ	AX, X9
+	PSHUFD	$0, X9, X9
+	bf16r_loop:
+		MOVUPS	(SI), X0
+		MOVUPS	16(SI), X1
+		ROUND(X0, X2, X3, X7)
+		ROUND(X1, X2, X3, X7)
+		// values fit 16 bits so unsigned saturation keeps them
+		PACKUSDW	X1, X0
+		MOVOU	X0, (DI)
+		ADDQ	$32, SI
+		ADDQ	$16, DI
+		DECQ	CX
+		JNE		bf16r_loop
+bf16r_end:
+	RET
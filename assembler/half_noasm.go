@@ -0,0 +1,19 @@
+//+build !amd64 noasm
+
+package assembler
+
+func F16toF32(dst []float32, src []uint16) {
+	f16tof32(dst[:len(src)], src)
+}
+
+func F32toF16(dst []uint16, src []float32) {
+	f32tof16(dst[:len(src)], src)
+}
+
+func BF16toF32(dst []float32, src []uint16) {
+	bf16tof32(dst[:len(src)], src)
+}
+
+func F32toBF16(dst []uint16, src []float32) {
+	f32tobf16(dst[:len(src)], src)
+}
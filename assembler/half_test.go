@@ -0,0 +1,135 @@
+package assembler
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func halfTestValues(n int) []float32 {
+	special := []float32{0, float32(math.Copysign(0, -1)), 1, -1, 65504, 65520, -65520, 1e-8, 6e-8, 3e-5, 0.1,
+		float32(math.Inf(1)), float32(math.Inf(-1)), float32(math.NaN()), math.MaxFloat32, math.SmallestNonzeroFloat32}
+	x := make([]float32, n)
+	for i := range x {
+		if i < len(special) {
+			x[i] = special[i]
+		} else {
+			x[i] = float32(rand.NormFloat64() * math.Pow(10, float64(rand.Intn(12)-6)))
+		}
+	}
+	return x
+}
+
+func TestFloat16Scalar(t *testing.T) {
+	// every half value survives round trip
+	for h := 0; h < 1<<16; h++ {
+		f := Float16toFloat32(uint16(h))
+		back := Float32toFloat16(f)
+		if math.IsNaN(float64(f)) {
+			if back&0x7c00 != 0x7c00 || back&0x3ff == 0 {
+				t.Fatalf("NaN %04x converted to %04x", h, back)
+			}
+			continue
+		}
+		if back != uint16(h) {
+			t.Fatalf("%04x -> %g -> %04x", h, f, back)
+		}
+	}
+	// conversion picks nearest half
+	for _, f := range halfTestValues(10000) {
+		h := Float32toFloat16(f)
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) || h&0x7fff >= 0x7bff {
+			continue
+		}
+		d := math.Abs(float64(Float16toFloat32(h) - f))
+		for _, n := range []uint16{h - 1, h + 1} {
+			if n&0x7fff == 0x7fff || (h&0x7fff == 0 && n == h-1) {
+				continue
+			}
+			if dn := math.Abs(float64(Float16toFloat32(n) - f)); dn < d {
+				t.Fatalf("%g converted to %04x but %04x is closer", f, h, n)
+			}
+		}
+	}
+}
+
+func TestBFloat16Scalar(t *testing.T) {
+	for h := 0; h < 1<<16; h++ {
+		f := BFloat16toFloat32(uint16(h))
+		if back := Float32toBFloat16(f); back != uint16(h) && !math.IsNaN(float64(f)) {
+			t.Fatalf("%04x -> %g -> %04x", h, f, back)
+		}
+	}
+	// ties go to even
+	if h := Float32toBFloat16(math.Float32frombits(0x3f808000)); h != 0x3f80 {
+		t.Fatalf("tie rounded to %04x", h)
+	}
+	if h := Float32toBFloat16(math.Float32frombits(0x3f818000)); h != 0x3f82 {
+		t.Fatalf("tie rounded to %04x", h)
+	}
+	if h := Float32toBFloat16(math.Float32frombits(0x7fffffff)); h&0x7f80 != 0x7f80 || h&0x7f == 0 {
+		t.Fatalf("NaN converted to %04x", h)
+	}
+}
+
+func narrowTest(f, ft func(dst []uint16, src []float32), t *testing.T) {
+	x := halfTestValues(100)
+	for n := 0; n < len(x); n++ {
+		fast, slow := make([]uint16, n), make([]uint16, n)
+		f(fast, x[:n])
+		ft(slow, x[:n])
+		for i := range fast {
+			if fast[i] != slow[i] {
+				t.Fatalf("%g converted to %04x but %04x expected", x[i], fast[i], slow[i])
+			}
+		}
+	}
+}
+
+func widenTest(f, ft func(dst []float32, src []uint16), t *testing.T) {
+	x := make([]uint16, 100)
+	for i := range x {
+		x[i] = uint16(rand.Intn(1 << 16))
+	}
+	for n := 0; n < len(x); n++ {
+		fast, slow := make([]float32, n), make([]float32, n)
+		f(fast, x[:n])
+		ft(slow, x[:n])
+		for i := range fast {
+			if math.Float32bits(fast[i]) != math.Float32bits(slow[i]) && !math.IsNaN(float64(slow[i])) {
+				t.Fatalf("%04x converted to %g but %g expected", x[i], fast[i], slow[i])
+			}
+		}
+	}
+}
+
+func TestHalfConversions(t *testing.T) {
+	narrowTest(F32toF16, f32tof16, t)
+	narrowTest(F32toBF16, f32tobf16, t)
+	widenTest(F16toF32, f16tof32, t)
+	widenTest(BF16toF32, bf16tof32, t)
+}
+
+func BenchmarkF32toF16(b *testing.B) {
+	x := halfTestValues(4096)
+	h := make([]uint16, len(x))
+	for i := 0; i < b.N; i++ {
+		F32toF16(h, x)
+	}
+}
+
+func BenchmarkF32toF16Generic(b *testing.B) {
+	x := halfTestValues(4096)
+	h := make([]uint16, len(x))
+	for i := 0; i < b.N; i++ {
+		f32tof16(h, x)
+	}
+}
+
+func BenchmarkF16toF32(b *testing.B) {
+	h := make([]uint16, 4096)
+	x := make([]float32, len(h))
+	for i := 0; i < b.N; i++ {
+		F16toF32(x, h)
+	}
+}
@@ -2,7 +2,6 @@ package gortex
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -48,35 +47,20 @@ func (d *BiDictionary) Top(n uint) {
 	d.Second = d.Second.Top(n)
 }
 
+// Save saves bidictionary as json, file is gzip compressed if name ends with GzipExtension
 func (d *BiDictionary) Save(name string) error {
-	// save MODEL_NAME
-	f, err := os.Create(name)
-	if err != nil {
-		return err
-	}
-	encoder := json.NewEncoder(f)
-	err = encoder.Encode(d)
-	if err != nil {
-		return err
-	}
-	f.Close()
-	return nil
+	return saveFile(name, d.Write)
 }
 
+// Load loads bidictionary saved by Save, gzip compressed or not
 func (*BiDictionary) Load(name string) (*BiDictionary, error) {
 	if len(name) == 0 {
 		return nil, fmt.Errorf("no file provided!")
 	}
-	f, e := os.Open(name)
-	if e != nil {
-		return nil, e
-	}
 	var d *BiDictionary
-	decoder := json.NewDecoder(f)
-	e = decoder.Decode(&d)
-	if e != nil {
-		return nil, e
-	}
-	f.Close()
-	return d, nil
+	e := loadFile(name, func(r *bufio.Reader) (e error) {
+		d, e = ReadBiDictionary(r)
+		return
+	})
+	return d, e
 }
@@ -0,0 +1,294 @@
+package gortex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+/*
+	Bundle file, all numbers are little endian
+
+	magic      [4]byte "GTXB"
+	size       uint32 size of header
+	header     JSON of architecture name, its config, tokenizer name and dictionary
+	model      binary model, see WriteModelBinary
+*/
+
+const BundleMagic = "GTXB"
+
+// ArchitectureBuilder makes model with fresh weights from JSON config and returns its parameters
+type ArchitectureBuilder func(config json.RawMessage) (model interface{}, parameters map[string]*Matrix, e error)
+
+var registry = struct {
+	sync.Mutex
+	architectures map[string]ArchitectureBuilder
+	tokenizers    map[string]Tokenizer
+}{
+	architectures: make(map[string]ArchitectureBuilder),
+	tokenizers:    make(map[string]Tokenizer),
+}
+
+// RegisterArchitecture makes architecture loadable from bundles, usually called from init
+func RegisterArchitecture(name string, build ArchitectureBuilder) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.architectures[name]; ok {
+		panic(fmt.Errorf("architecture %s is already registered", name))
+	}
+	registry.architectures[name] = build
+}
+
+// RegisterTokenizer makes tokenizer loadable from bundles
+func RegisterTokenizer(name string, tokenizer Tokenizer) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.tokenizers[name]; ok {
+		panic(fmt.Errorf("tokenizer %s is already registered", name))
+	}
+	registry.tokenizers[name] = tokenizer
+}
+
+// Architectures lists registered architecture names
+func Architectures() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	names := make([]string, 0, len(registry.architectures))
+	for name := range registry.architectures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func tokenizerName(tokenizer Tokenizer) (string, error) {
+	registry.Lock()
+	defer registry.Unlock()
+	for name, t := range registry.tokenizers {
+		if reflect.TypeOf(t) == reflect.TypeOf(tokenizer) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("tokenizer %T is not registered", tokenizer)
+}
+
+// CellConfig is constructor arguments of cells registered under their type names
+type CellConfig struct {
+	Namespace   string // parameters namespace, architecture name if empty
+	Input       int
+	Hidden      int
+	Output      int
+	Layers      int   // IndRNN
+	Length      int   // IndRNN
+	KernelSizes []int // DilatedTemporalConvolution
+	UseGates    bool  // DilatedTemporalConvolution
+	// LSTM, GRU and MultiplicativeLSTM
+	Dropout *RecurrentDropout `json:",omitempty"`
+}
+
+func registerCell(name string, makeCell func(c *CellConfig) Parameterized) {
+	RegisterArchitecture(name, func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(CellConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("%s config: %v", name, e)
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = name
+		}
+		cell := makeCell(c)
+		return cell, cell.GetParameters(c.Namespace), nil
+	})
+}
+
+func init() {
+	registerCell("LSTM", func(c *CellConfig) Parameterized {
+		rnn := MakeLSTM(c.Input, c.Hidden, c.Output)
+		rnn.Dropout = c.Dropout
+		return rnn
+	})
+	registerCell("GRU", func(c *CellConfig) Parameterized {
+		rnn := MakeGRU(c.Input, c.Hidden, c.Output)
+		rnn.Dropout = c.Dropout
+		return rnn
+	})
+	registerCell("LayerNormLSTM", func(c *CellConfig) Parameterized {
+		return MakeLayerNormLSTM(c.Input, c.Hidden, c.Output)
+	})
+	registerCell("LayerNormGRU", func(c *CellConfig) Parameterized {
+		return MakeLayerNormGRU(c.Input, c.Hidden, c.Output)
+	})
+	registerCell("PeepholeLSTM", func(c *CellConfig) Parameterized {
+		return MakePeepholeLSTM(c.Input, c.Hidden, c.Output)
+	})
+	registerCell("MGU", func(c *CellConfig) Parameterized { return MakeMGU(c.Input, c.Hidden, c.Output) })
+	registerCell("SRU", func(c *CellConfig) Parameterized { return MakeSRU(c.Input, c.Hidden, c.Output) })
+	registerCell("QRNN", func(c *CellConfig) Parameterized { return MakeQRNN(c.Input, c.Hidden, c.Output) })
+	registerCell("RNN", func(c *CellConfig) Parameterized { return MakeRNN(c.Input, c.Hidden, c.Output) })
+	registerCell("DeltaRNN", func(c *CellConfig) Parameterized { return MakeDeltaRNN(c.Input, c.Hidden, c.Output) })
+	registerCell("MultiplicativeLSTM", func(c *CellConfig) Parameterized {
+		rnn := MakeMultiplicativeLSTM(c.Input, c.Hidden, c.Output)
+		rnn.Dropout = c.Dropout
+		return rnn
+	})
+	registerCell("MultiplicativeNestedLSTM", func(c *CellConfig) Parameterized {
+		return MakeMultiplicativeNestedLSTM(c.Input, c.Hidden, c.Output)
+	})
+	registerCell("OutputlessLSTM", func(c *CellConfig) Parameterized { return MakeOutputlessLSTM(c.Input, c.Hidden) })
+	registerCell("OutputlessGRU", func(c *CellConfig) Parameterized { return MakeOutputlessGRU(c.Input, c.Hidden) })
+	registerCell("OutputlessRNN", func(c *CellConfig) Parameterized { return MakeOutputlessRNN(c.Input, c.Hidden) })
+	registerCell("InputlessGRU", func(c *CellConfig) Parameterized { return MakeInputlessGRU(c.Hidden, c.Output) })
+	registerCell("IndRNN", func(c *CellConfig) Parameterized {
+		return MakeIndRNN(c.Layers, c.Input, c.Length, c.Hidden, c.Output)
+	})
+	registerCell("VAE", func(c *CellConfig) Parameterized { return MakeVae(c.Input, c.Hidden) })
+	registerCell("DilatedTemporalConvolution", func(c *CellConfig) Parameterized {
+		return MakeDilatedTemporalConvolution(c.Input, c.KernelSizes, c.UseGates)
+	})
+
+	RegisterTokenizer("WhiteSpaceSplitter", WhiteSpaceSplitter{})
+	RegisterTokenizer("CharSplitter", CharSplitter{})
+	RegisterTokenizer("WordSplitter", WordSplitter{})
+}
+
+// Bundle is self describing model, it keeps everything needed to run model
+type Bundle struct {
+	Architecture  string
+	Config        json.RawMessage // constructor arguments
+	TokenizerName string          `json:"Tokenizer,omitempty"`
+	Dictionary    *Dictionary     `json:",omitempty"`
+
+	Model      interface{}        `json:"-"` // model made by architecture builder, e.g. *LSTM
+	Parameters map[string]*Matrix `json:"-"` // parameters of Model
+	Tokenizer  Tokenizer          `json:"-"` // tokenizer of model, restored by LoadBundle
+}
+
+// NewBundle makes model of registered architecture with fresh weights
+func NewBundle(architecture string, config interface{}) (*Bundle, error) {
+	raw, e := json.Marshal(config)
+	if e != nil {
+		return nil, e
+	}
+	b := &Bundle{Architecture: architecture, Config: raw}
+	if e = b.build(); e != nil {
+		return nil, e
+	}
+	return b, nil
+}
+
+func (b *Bundle) build() (e error) {
+	registry.Lock()
+	build, ok := registry.architectures[b.Architecture]
+	registry.Unlock()
+	if !ok {
+		return fmt.Errorf("architecture %s is not registered, known are %v", b.Architecture, Architectures())
+	}
+	b.Model, b.Parameters, e = build(b.Config)
+	return
+}
+
+// WriteBundle writes bundle header and model parameters
+func WriteBundle(w io.Writer, b *Bundle) (e error) {
+	b.TokenizerName = ""
+	if b.Tokenizer != nil {
+		if b.TokenizerName, e = tokenizerName(b.Tokenizer); e != nil {
+			return e
+		}
+	}
+	header, e := json.Marshal(b)
+	if e != nil {
+		return e
+	}
+	if len(header) > maxBundleHeader {
+		return fmt.Errorf("bundle header of %d bytes is too large", len(header))
+	}
+	var scratch [4]byte
+	if _, e = io.WriteString(w, BundleMagic); e != nil {
+		return e
+	}
+	binary.LittleEndian.PutUint32(scratch[:], uint32(len(header)))
+	if _, e = w.Write(scratch[:]); e != nil {
+		return e
+	}
+	if _, e = w.Write(header); e != nil {
+		return e
+	}
+	return WriteModelBinary(w, b.Parameters)
+}
+
+// maxBundleHeader bounds json header of bundle, dictionary of millions of tokens fits it
+const maxBundleHeader = 100 << 20
+
+// ReadBundle reads bundle and rebuilds its model with stored weights
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	var preamble [8]byte
+	if _, e := io.ReadFull(r, preamble[:]); e != nil {
+		return nil, e
+	}
+	if string(preamble[:4]) != BundleMagic {
+		return nil, fmt.Errorf("not a model bundle, magic %q", preamble[:4])
+	}
+	size := binary.LittleEndian.Uint32(preamble[4:])
+	if size > maxBundleHeader {
+		return nil, fmt.Errorf("bundle header of %d bytes is too large", size)
+	}
+	header := make([]byte, size)
+	if _, e := io.ReadFull(r, header); e != nil {
+		return nil, e
+	}
+	b := new(Bundle)
+	if e := json.Unmarshal(header, b); e != nil {
+		return nil, fmt.Errorf("broken bundle header: %v", e)
+	}
+	if len(b.TokenizerName) > 0 {
+		registry.Lock()
+		b.Tokenizer = registry.tokenizers[b.TokenizerName]
+		registry.Unlock()
+		if b.Tokenizer == nil {
+			return nil, fmt.Errorf("tokenizer %s is not registered", b.TokenizerName)
+		}
+	}
+	if e := b.build(); e != nil {
+		return nil, e
+	}
+	stored, e := ReadModelBinary(r)
+	if e != nil {
+		return nil, e
+	}
+	if e = SetParameters(b.Parameters, stored); e != nil {
+		return nil, fmt.Errorf("bundle of %s: %v", b.Architecture, e)
+	}
+	return b, nil
+}
+
+// Save saves bundle to file
+func (b *Bundle) Save(name string) error {
+	f, e := os.Create(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+	if e = WriteBundle(bw, b); e != nil {
+		return e
+	}
+	if e = bw.Flush(); e != nil {
+		return e
+	}
+	return f.Close()
+}
+
+// LoadBundle loads bundle from file, b.Model is ready to run model of stored architecture
+func LoadBundle(name string) (*Bundle, error) {
+	f, e := os.Open(name)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	return ReadBundle(bufio.NewReader(f))
+}
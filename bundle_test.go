@@ -0,0 +1,98 @@
+package gortex
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	dic := NewDictionary()
+	dic.Add("hello")
+	b, e := NewBundle("MultiplicativeLSTM", &CellConfig{Namespace: "LM", Input: 4, Hidden: 5, Output: dic.Len()})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.Dictionary = dic
+	b.Tokenizer = WhiteSpaceSplitter{}
+	name := filepath.Join(t.TempDir(), "lm.gtx")
+	if e = b.Save(name); e != nil {
+		t.Fatal(e)
+	}
+	loaded, e := LoadBundle(name)
+	if e != nil {
+		t.Fatal(e)
+	}
+	lstm, ok := loaded.Model.(*MultiplicativeLSTM)
+	if !ok {
+		t.Fatalf("%T loaded instead of *MultiplicativeLSTM", loaded.Model)
+	}
+	original := b.Model.(*MultiplicativeLSTM)
+	if lstm.Wf.Rows != 5 || lstm.Wf.Columns != 4 || lstm.Who.Rows != dic.Len() {
+		t.Fatal("wrong geometry of loaded model")
+	}
+	for k, v := range b.Parameters {
+		for i := range v.W {
+			if loaded.Parameters[k].W[i] != v.W[i] {
+				t.Fatalf("parameter %s weight %d mismatch", k, i)
+			}
+		}
+	}
+	if lstm.Wf.W[0] != original.Wf.W[0] || len(lstm.Wf.DW) != len(lstm.Wf.W) {
+		t.Fatal("loaded model does not use loaded weights")
+	}
+	if _, ok := loaded.Tokenizer.(WhiteSpaceSplitter); !ok || loaded.Dictionary.IDByToken("hello") != dic.IDByToken("hello") {
+		t.Fatal("tokenizer or dictionary is not restored")
+	}
+	if _, e = NewBundle("Transformer", nil); e == nil {
+		t.Fatal("unknown architecture must be reported")
+	}
+	if _, e = ReadBundle(strings.NewReader(BundleMagic + "\xff\xff\xff\xff{}")); e == nil || !strings.Contains(e.Error(), "too large") {
+		t.Fatalf("huge header must be reported before it is read but %v", e)
+	}
+}
+
+func TestLanguageModelBundle(t *testing.T) {
+	dic := NewDictionary()
+	for _, token := range []string{"a", "b", "c"} {
+		dic.Add(token)
+	}
+	config := &LanguageModelConfig{Vocabulary: dic.Len(), Embedding: 4, Cell: LayerConfig{
+		Architecture: "GRU", Config: []byte(`{"Input": 4, "Hidden": 5, "Output": 3}`)}}
+	b, e := NewBundle("LanguageModel", config)
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.Dictionary, b.Tokenizer = dic, WhiteSpaceSplitter{}
+	lm := b.Model.(*LanguageModel)
+	if b.Parameters["LanguageModel_Embedding"] != lm.Embedding || b.Parameters["LanguageModel_Head_W"] == nil ||
+		b.Parameters["LanguageModel_Cell_Wz"] == nil {
+		t.Fatalf("embedding, cell and head must be bundled but %d parameters found", len(b.Parameters))
+	}
+	name := filepath.Join(t.TempDir(), "lm.gtx")
+	if e = b.Save(name); e != nil {
+		t.Fatal(e)
+	}
+	loaded, e := LoadBundle(name)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var tokens []int
+	for _, token := range loaded.Tokenizer.Split("a c b") {
+		tokens = append(tokens, int(loaded.Dictionary.IDByToken(token)))
+	}
+	expected, actual := lm.Forward(&Graph{}, tokens), loaded.Model.(*LanguageModel).Forward(&Graph{}, tokens)
+	if len(actual) != 3 || actual[2].Rows != dic.Len() {
+		t.Fatal("wrong logits of loaded language model")
+	}
+	for i := range expected[2].W {
+		if expected[2].W[i] != actual[2].W[i] {
+			t.Fatal("loaded language model differs from saved one")
+		}
+	}
+
+	// cell producing logits itself needs no head
+	if lm := MakeLanguageModel(dic.Len(), 4, MakeLSTM(4, 5, dic.Len())); lm.Head != nil {
+		t.Fatal("head is not needed")
+	}
+}
@@ -0,0 +1,342 @@
+package gortex
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// checkpoint is directory of files, it is written under temporary name and renamed when complete
+const (
+	checkpointPrefix     = "checkpoint-"
+	checkpointTemporary  = ".tmp"
+	checkpointReplaced   = ".old" // checkpoint of the same step while it is being replaced
+	checkpointModel      = "model.bin"
+	checkpointOptimizer  = "optimizer.bin"
+	checkpointOpState    = "optimizer.json"
+	checkpointDictionary = "dictionary.json"
+	checkpointMeta       = "meta.json"
+)
+
+// CheckpointMeta is saved as JSON next to checkpoint data
+type CheckpointMeta struct {
+	Step            int
+	Epoch           int
+	Metric          float32 // used to track best checkpoints
+	Time            time.Time
+	Hyperparameters map[string]interface{}
+}
+
+// Checkpoint bundles everything needed to resume training, Optimizer and Dictionary are optional
+type Checkpoint struct {
+	Meta       CheckpointMeta
+	Model      map[string]*Matrix
+	Optimizer  *Optimizer
+	Dictionary *Dictionary
+	Path       string // directory checkpoint was saved to or loaded from
+}
+
+// CheckpointManager saves checkpoints atomically into Dir keeping KeepLast latest and KeepBest best of them
+type CheckpointManager struct {
+	Dir            string
+	KeepLast       int
+	KeepBest       int
+	HigherIsBetter bool // metric is accuracy like, otherwise loss like
+}
+
+func NewCheckpointManager(dir string, keepLast, keepBest int) (*CheckpointManager, error) {
+	if keepLast < 1 {
+		return nil, fmt.Errorf("at least one latest checkpoint must be kept but %d requested", keepLast)
+	}
+	if e := os.MkdirAll(dir, 0755); e != nil {
+		return nil, e
+	}
+	return &CheckpointManager{Dir: dir, KeepLast: keepLast, KeepBest: keepBest}, nil
+}
+
+// writeFileSync writes file and flushes it to disk
+func writeFileSync(name string, write func(w io.Writer) error) error {
+	f, e := os.Create(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+	if e = write(bw); e != nil {
+		return e
+	}
+	if e = bw.Flush(); e != nil {
+		return e
+	}
+	if e = f.Sync(); e != nil {
+		return e
+	}
+	return f.Close()
+}
+
+// syncDir flushes directory entries, not every platform can sync directories so errors are ignored
+func syncDir(name string) {
+	if d, e := os.Open(name); e == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// optimizerState is optimizer settings, per parameter state goes to binary file
+type optimizerState struct {
+	OpOp
+	Iteration float32
+}
+
+func optimizerMatrices(o *Optimizer) map[string]*Matrix {
+	m := make(map[string]*Matrix, len(o.PreviousGradient)+len(o.PreviousWeight))
+	for name, v := range o.PreviousGradient {
+		m["PreviousGradient_"+name] = &Matrix{Rows: len(v), Columns: 1, W: v}
+	}
+	for name, v := range o.PreviousWeight {
+		m["PreviousWeight_"+name] = &Matrix{Rows: len(v), Columns: 1, W: v}
+	}
+	return m
+}
+
+// Save writes checkpoint into temporary directory, flushes it to disk and renames it,
+// then removes checkpoints not worth keeping. Checkpoint of the same step is renamed aside
+// before it is replaced so there is always complete checkpoint on disk
+func (cm *CheckpointManager) Save(c *Checkpoint) error {
+	if c.Meta.Time.IsZero() {
+		c.Meta.Time = time.Now()
+	}
+	name := filepath.Join(cm.Dir, fmt.Sprintf("%s%09d", checkpointPrefix, c.Meta.Step))
+	tmp := name + checkpointTemporary
+	if e := os.RemoveAll(tmp); e != nil {
+		return e
+	}
+	if e := os.Mkdir(tmp, 0755); e != nil {
+		return e
+	}
+	files := map[string]func(w io.Writer) error{
+		checkpointModel: func(w io.Writer) error { return WriteModelBinary(w, c.Model) },
+		checkpointMeta:  func(w io.Writer) error { return writeJSON(w, &c.Meta) },
+	}
+	if c.Optimizer != nil {
+		files[checkpointOptimizer] = func(w io.Writer) error { return WriteModelBinary(w, optimizerMatrices(c.Optimizer)) }
+		files[checkpointOpState] = func(w io.Writer) error {
+			return writeJSON(w, &optimizerState{OpOp: c.Optimizer.OpOp, Iteration: c.Optimizer.Iteration})
+		}
+	}
+	if c.Dictionary != nil {
+		files[checkpointDictionary] = func(w io.Writer) error { return writeJSON(w, c.Dictionary) }
+	}
+	for file, write := range files {
+		if e := writeFileSync(filepath.Join(tmp, file), write); e != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("checkpoint %s: %v", file, e)
+		}
+	}
+	syncDir(tmp)
+	old := name + checkpointReplaced
+	if e := os.RemoveAll(old); e != nil {
+		return e
+	}
+	if e := os.Rename(name, old); e != nil && !os.IsNotExist(e) {
+		return e
+	}
+	if e := os.Rename(tmp, name); e != nil {
+		// put replaced checkpoint back
+		os.Rename(old, name)
+		return e
+	}
+	syncDir(cm.Dir)
+	if e := os.RemoveAll(old); e != nil {
+		return e
+	}
+	c.Path = name
+	return cm.rotate()
+}
+
+// restore brings back checkpoints renamed aside by Save interrupted before their replacement
+// took place, replaced ones are removed
+func (cm *CheckpointManager) restore() {
+	replaced, _ := filepath.Glob(filepath.Join(cm.Dir, checkpointPrefix+"*"+checkpointReplaced))
+	for _, old := range replaced {
+		name := strings.TrimSuffix(old, checkpointReplaced)
+		if _, e := os.Stat(name); os.IsNotExist(e) {
+			if e = os.Rename(old, name); e == nil {
+				syncDir(cm.Dir)
+				continue
+			}
+		}
+		os.RemoveAll(old)
+	}
+}
+
+// list returns metadata of complete checkpoints sorted by step, Path is set too
+func (cm *CheckpointManager) list() ([]*Checkpoint, error) {
+	cm.restore()
+	entries, e := ioutil.ReadDir(cm.Dir)
+	if e != nil {
+		return nil, e
+	}
+	var checkpoints []*Checkpoint
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(name, checkpointPrefix) ||
+			strings.HasSuffix(name, checkpointTemporary) || strings.HasSuffix(name, checkpointReplaced) {
+			continue
+		}
+		c := &Checkpoint{Path: filepath.Join(cm.Dir, name)}
+		if e := readJSON(filepath.Join(c.Path, checkpointMeta), &c.Meta); e != nil {
+			log.Printf("WARNING: skip checkpoint %s: %v", c.Path, e)
+			continue
+		}
+		checkpoints = append(checkpoints, c)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Meta.Step < checkpoints[j].Meta.Step })
+	return checkpoints, nil
+}
+
+func (cm *CheckpointManager) better(a, b *Checkpoint) bool {
+	if cm.HigherIsBetter {
+		return a.Meta.Metric > b.Meta.Metric
+	}
+	return a.Meta.Metric < b.Meta.Metric
+}
+
+// rotate removes checkpoints which are neither latest nor best and leftovers of interrupted saves
+func (cm *CheckpointManager) rotate() error {
+	checkpoints, e := cm.list()
+	if e != nil {
+		return e
+	}
+	keep := make(map[string]bool)
+	for i := len(checkpoints) - 1; i >= 0 && i >= len(checkpoints)-cm.KeepLast; i-- {
+		keep[checkpoints[i].Path] = true
+	}
+	best := make([]*Checkpoint, len(checkpoints))
+	copy(best, checkpoints)
+	sort.SliceStable(best, func(i, j int) bool { return cm.better(best[i], best[j]) })
+	for i := 0; i < cm.KeepBest && i < len(best); i++ {
+		keep[best[i].Path] = true
+	}
+	for _, c := range checkpoints {
+		if !keep[c.Path] {
+			if e = os.RemoveAll(c.Path); e != nil {
+				return e
+			}
+		}
+	}
+	temporary, _ := filepath.Glob(filepath.Join(cm.Dir, checkpointPrefix+"*"+checkpointTemporary))
+	for _, name := range temporary {
+		os.RemoveAll(name)
+	}
+	return nil
+}
+
+func readJSON(name string, v interface{}) error {
+	f, e := os.Open(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	return json.NewDecoder(bufio.NewReader(f)).Decode(v)
+}
+
+func readModelFile(name string) (map[string]*Matrix, error) {
+	f, e := os.Open(name)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	return ReadModelBinary(bufio.NewReader(f))
+}
+
+// LoadCheckpoint loads and verifies checkpoint directory, model gets gradient memory allocated
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	c := &Checkpoint{Path: path}
+	if e := readJSON(filepath.Join(path, checkpointMeta), &c.Meta); e != nil {
+		return nil, e
+	}
+	model, e := readModelFile(filepath.Join(path, checkpointModel))
+	if e != nil {
+		return nil, e
+	}
+	for _, m := range model {
+		m.DW = make([]float32, len(m.W))
+	}
+	c.Model = model
+
+	state := new(optimizerState)
+	if e = readJSON(filepath.Join(path, checkpointOpState), state); e == nil {
+		matrices, e := readModelFile(filepath.Join(path, checkpointOptimizer))
+		if e != nil {
+			return nil, e
+		}
+		c.Optimizer = NewOptimizer(state.OpOp)
+		c.Optimizer.Iteration = state.Iteration
+		for name, m := range matrices {
+			switch {
+			case strings.HasPrefix(name, "PreviousGradient_"):
+				c.Optimizer.PreviousGradient[strings.TrimPrefix(name, "PreviousGradient_")] = m.W
+			case strings.HasPrefix(name, "PreviousWeight_"):
+				c.Optimizer.PreviousWeight[strings.TrimPrefix(name, "PreviousWeight_")] = m.W
+			}
+		}
+	} else if !os.IsNotExist(e) {
+		return nil, e
+	}
+
+	dictionary := new(Dictionary)
+	if e = readJSON(filepath.Join(path, checkpointDictionary), dictionary); e == nil {
+		c.Dictionary = dictionary
+	} else if !os.IsNotExist(e) {
+		return nil, e
+	}
+	return c, nil
+}
+
+// Latest loads most recent checkpoint which passes verification, broken ones are reported and skipped
+func (cm *CheckpointManager) Latest() (*Checkpoint, error) {
+	checkpoints, e := cm.list()
+	if e != nil {
+		return nil, e
+	}
+	for i := len(checkpoints) - 1; i >= 0; i-- {
+		c, e := LoadCheckpoint(checkpoints[i].Path)
+		if e != nil {
+			log.Printf("WARNING: skip broken checkpoint %s: %v", checkpoints[i].Path, e)
+			continue
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("no valid checkpoint in %s", cm.Dir)
+}
+
+// Best loads checkpoint with best metric which passes verification
+func (cm *CheckpointManager) Best() (*Checkpoint, error) {
+	checkpoints, e := cm.list()
+	if e != nil {
+		return nil, e
+	}
+	sort.SliceStable(checkpoints, func(i, j int) bool { return cm.better(checkpoints[i], checkpoints[j]) })
+	for _, checkpoint := range checkpoints {
+		c, e := LoadCheckpoint(checkpoint.Path)
+		if e != nil {
+			log.Printf("WARNING: skip broken checkpoint %s: %v", checkpoint.Path, e)
+			continue
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("no valid checkpoint in %s", cm.Dir)
+}
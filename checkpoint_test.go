@@ -0,0 +1,100 @@
+package gortex
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointManager(t *testing.T) {
+	dir := t.TempDir()
+	cm, e := NewCheckpointManager(dir, 2, 1)
+	if e != nil {
+		t.Fatal(e)
+	}
+	model := map[string]*Matrix{"Encoder_Wf": RandMat(3, 4), "Encoder_Bf": RandMat(3, 1)}
+	optimizer := NewOptimizer(OpOp{Method: ADAM, LearningRate: 0.001})
+	optimizer.PreviousGradient["Encoder_Wf"] = make([]float32, 12)
+	optimizer.PreviousGradient["Encoder_Wf"][5] = 0.5
+	optimizer.Iteration = 7
+	dictionary := NewDictionary()
+	dictionary.Add("hello")
+	// step 1 has best (lowest) loss
+	for i, loss := range []float32{1, 5, 4, 3, 2} {
+		c := &Checkpoint{Meta: CheckpointMeta{Step: i + 1, Metric: loss, Hyperparameters: map[string]interface{}{"lr": 0.001}},
+			Model: model, Optimizer: optimizer, Dictionary: dictionary}
+		if e = cm.Save(c); e != nil {
+			t.Fatal(e)
+		}
+	}
+	// leftover of interrupted save is removed on next rotation
+	os.Mkdir(filepath.Join(dir, checkpointPrefix+"000000009"+checkpointTemporary), 0755)
+	checkpoints, e := cm.list()
+	if e != nil {
+		t.Fatal(e)
+	}
+	var steps []int
+	for _, c := range checkpoints {
+		steps = append(steps, c.Meta.Step)
+	}
+	if len(steps) != 3 || steps[0] != 1 || steps[1] != 4 || steps[2] != 5 {
+		t.Fatalf("checkpoints of steps %v are kept, [1 4 5] expected", steps)
+	}
+
+	latest, e := cm.Latest()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if latest.Meta.Step != 5 || latest.Meta.Hyperparameters["lr"] != 0.001 {
+		t.Fatalf("latest checkpoint meta %+v", latest.Meta)
+	}
+	if latest.Model["Encoder_Wf"].W[3] != model["Encoder_Wf"].W[3] || len(latest.Model["Encoder_Wf"].DW) != 12 {
+		t.Fatal("model is not restored")
+	}
+	if latest.Optimizer.Method != ADAM || latest.Optimizer.Iteration != 7 || latest.Optimizer.PreviousGradient["Encoder_Wf"][5] != 0.5 {
+		t.Fatal("optimizer state is not restored")
+	}
+	if latest.Dictionary.IDByToken("hello") != dictionary.IDByToken("hello") {
+		t.Fatal("dictionary is not restored")
+	}
+
+	// corrupt latest model, previous checkpoint must be restored
+	name := filepath.Join(latest.Path, checkpointModel)
+	b, _ := os.ReadFile(name)
+	b[len(b)-8] ^= 0xff
+	os.WriteFile(name, b, 0644)
+	if latest, e = cm.Latest(); e != nil {
+		t.Fatal(e)
+	}
+	if latest.Meta.Step != 4 {
+		t.Fatalf("checkpoint of step %d restored, 4 expected", latest.Meta.Step)
+	}
+	best, e := cm.Best()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if best.Meta.Step != 1 {
+		t.Fatalf("best checkpoint has step %d, 1 expected", best.Meta.Step)
+	}
+	if e = cm.Save(&Checkpoint{Meta: CheckpointMeta{Step: 6, Metric: 9}, Model: model}); e != nil {
+		t.Fatal(e)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*"+checkpointTemporary)); len(matches) > 0 {
+		t.Fatalf("temporary checkpoints %v are not removed", matches)
+	}
+
+	// checkpoint of the same step is replaced, one renamed aside by interrupted replace is restored
+	if e = cm.Save(&Checkpoint{Meta: CheckpointMeta{Step: 6, Metric: 8}, Model: model}); e != nil {
+		t.Fatal(e)
+	}
+	name = filepath.Join(dir, checkpointPrefix+"000000006")
+	if e = os.Rename(name, name+checkpointReplaced); e != nil {
+		t.Fatal(e)
+	}
+	if latest, e = cm.Latest(); e != nil || latest.Meta.Step != 6 || latest.Meta.Metric != 8 {
+		t.Fatalf("replaced checkpoint is not restored: %v", e)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*"+checkpointReplaced)); len(matches) > 0 {
+		t.Fatalf("replaced checkpoints %v are left", matches)
+	}
+}
@@ -2,7 +2,6 @@ package gortex
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
@@ -75,31 +74,14 @@ func (d *Dictionary) Add(token string) {
 	}
 }
 
+// Save saves dictionary as json, file is gzip compressed if name ends with GzipExtension
 func (d *Dictionary) Save(name string) error {
-	f, err := os.Create(name)
-	if err != nil {
-		return err
-	}
-	encoder := json.NewEncoder(f)
-	err = encoder.Encode(d)
-	if err != nil {
-		return err
-	}
-	f.Close()
-	return nil
+	return saveFile(name, d.Write)
 }
 
+// Load loads dictionary saved by Save, gzip compressed or not
 func (d *Dictionary) Load(name string) error {
-	if f, e := os.Open(name); e != nil {
-		return e
-	} else {
-		defer f.Close()
-		decoder := json.NewDecoder(f)
-		if e = decoder.Decode(d); e != nil {
-			return e
-		}
-	}
-	return nil
+	return loadFile(name, func(r *bufio.Reader) error { return d.Read(r) })
 }
 
 func (d *Dictionary) Print(n int) {
@@ -118,18 +100,12 @@ func LoadDictionary(name string) (*Dictionary, error) {
 	if len(name) == 0 {
 		return nil, fmt.Errorf("No dictionary file provided! [%s]", name)
 	}
-	f, e := os.Open(name)
-	if e != nil {
-		return nil, e
-	}
-	var m *Dictionary
-	decoder := json.NewDecoder(f)
-	e = decoder.Decode(&m)
-	if e != nil {
-		return nil, e
-	}
-	f.Close()
-	return m, nil
+	var d *Dictionary
+	e := loadFile(name, func(r *bufio.Reader) (e error) {
+		d, e = ReadDictionary(r)
+		return
+	})
+	return d, e
 }
 
 func (d *Dictionary) String() string {
@@ -17,8 +17,9 @@ type DilatedTemporalConvolution struct {
 	Zeros       []*Matrix
 }
 
-func MakeDilatedTemporalConvolution(inputSize int, kernelSizes []int, useGates bool) *DilatedTemporalConvolution {
+func MakeDilatedTemporalConvolution(inputSize int, kernelSizes []int, useGates bool, options ...InitOption) *DilatedTemporalConvolution {
 	dtc := new(DilatedTemporalConvolution)
+	in := NewInitialization(Normal{0, 0.1}, Normal{0, 0.1}, Constant{}, options...)
 	dtc.UseGates = useGates
 	dtc.KernelSizes = kernelSizes
 
@@ -34,12 +35,12 @@ func MakeDilatedTemporalConvolution(inputSize int, kernelSizes []int, useGates b
 	for i, n := range kernelSizes {
 		dtc.Kernels[i] = make([]*Matrix, n)
 		for j := range dtc.Kernels[i] {
-			dtc.Kernels[i][j] = RandMatMD(inputSizes[i], 3, 0, 0.1)
+			dtc.Kernels[i][j] = in.weights(inputSizes[i], 3)
 		}
-		dtc.ConvBiases[i] = Mat(kernelSizes[i], 1)
+		dtc.ConvBiases[i] = in.biases(kernelSizes[i])
 		if dtc.UseGates {
-			dtc.Gates[i] = RandMatMD(kernelSizes[i], kernelSizes[i], 0, 0.1)
-			dtc.Biases[i] = Mat(kernelSizes[i], 1)
+			dtc.Gates[i] = in.weights(kernelSizes[i], kernelSizes[i])
+			dtc.Biases[i] = in.biases(kernelSizes[i])
 		}
 	}
 
@@ -88,16 +89,7 @@ func (dtc *DilatedTemporalConvolution) ReceptiveField(i, layer int) (field []int
 }
 
 func (dtc *DilatedTemporalConvolution) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range dtc.GetParameters(namespace) {
-		//fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			//fmt.Printf("Got %s parameters\n", k)
-			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(dtc.GetParameters(namespace), parameters)
 }
 
 func (dtc *DilatedTemporalConvolution) GetParameters(namespace string) map[string]*Matrix {
@@ -2,13 +2,15 @@ package gortex
 
 import "math"
 import (
-	"encoding/json"
+	"bufio"
 	"fmt"
+	"io"
 
 	"github.com/vseledkin/gortex/assembler"
 
 	"math/rand"
-	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -180,38 +182,33 @@ func Multinomial(probabilities *Matrix) uint {
 	return uint(len(probabilities.W) - 1)
 }
 
+// SaveModel saves model in binary format, see WriteModelBinary,
+// file is gzip compressed if name ends with GzipExtension
 func SaveModel(name string, m map[string]*Matrix) error {
-	// save MODEL_NAME
-	f, err := os.Create(name)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	encoder := json.NewEncoder(f)
-	err = encoder.Encode(m)
-	if err != nil {
-		return err
-	}
-	return nil
+	return saveFile(name, func(w io.Writer) error { return WriteModelBinary(w, m) })
+}
+
+// SaveModelAs saves model in binary format converting every tensor to dtype
+func SaveModelAs(name string, m map[string]*Matrix, dtype DType) error {
+	return saveFile(name, func(w io.Writer) error { return WriteModelBinaryAs(w, m, dtype) })
+}
+
+// SaveModelJSON saves model in legacy json format
+func SaveModelJSON(name string, m map[string]*Matrix) error {
+	return saveFile(name, func(w io.Writer) error { return WriteModelJSON(w, m) })
 }
 
+// LoadModel loads model saved in binary or legacy json format, gzip compressed or not
 func LoadModel(name string) (map[string]*Matrix, error) {
 	if len(name) == 0 {
 		return nil, fmt.Errorf("No model file provided! [%s]", name)
 	}
-	//fmt.Printf("Loading learned model %s\n", name)
-	f, e := os.Open(name)
-	if e != nil {
-		return nil, e
-	}
-	defer f.Close()
 	var m map[string]*Matrix
-	decoder := json.NewDecoder(f)
-	err := decoder.Decode(&m)
-	if err != nil {
-		return nil, err
-	}
-	return m, nil
+	e := loadFile(name, func(r *bufio.Reader) (e error) {
+		m, e = ReadModel(r)
+		return
+	})
+	return m, e
 }
 
 func F1Score(trueLabels, predictedLabels []uint, str []string, excludes map[uint]bool) (float64, string) {
@@ -301,15 +298,101 @@ func F1Score(trueLabels, predictedLabels []uint, str []string, excludes map[uint
 	return F, message
 }
 
-func SetParameters(dest, parameters map[string]*Matrix) error {
+// ParameterError reports every parameter which can not be loaded
+type ParameterError struct {
+	Missing    []string // expected but not given
+	Unexpected []string // given but not expected
+	Mismatched []string // given with wrong shape or storage size, with details
+}
+
+func (pe *ParameterError) Error() string {
+	var problems []string
+	if len(pe.Missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(pe.Missing, ", "))
+	}
+	if len(pe.Unexpected) > 0 {
+		problems = append(problems, "unexpected "+strings.Join(pe.Unexpected, ", "))
+	}
+	if len(pe.Mismatched) > 0 {
+		problems = append(problems, "mismatched "+strings.Join(pe.Mismatched, ", "))
+	}
+	return "Model geometry is not compatible: " + strings.Join(problems, "; ")
+}
+
+// checkParameters compares parameters to dest, given names absent in dest are reported
+// as unexpected only if unexpected is true
+func checkParameters(dest, parameters map[string]*Matrix, unexpected bool) *ParameterError {
+	pe := new(ParameterError)
 	for k, v := range dest {
-		if m, ok := parameters[k]; ok {
+		m, ok := parameters[k]
+		switch {
+		case !ok:
+			pe.Missing = append(pe.Missing, k)
+		case storage(m) != storage(v):
+			pe.Mismatched = append(pe.Mismatched, fmt.Sprintf("%s is %s but %s expected", k, storage(m), storage(v)))
+		case len(m.W)+len(m.Half) != m.Rows*m.Columns:
+			pe.Mismatched = append(pe.Mismatched, fmt.Sprintf("%s has %d weights for %dx%d", k, len(m.W)+len(m.Half), m.Rows, m.Columns))
+		case m.Rows != v.Rows || m.Columns != v.Columns:
+			pe.Mismatched = append(pe.Mismatched, fmt.Sprintf("%s is %dx%d but %dx%d expected", k, m.Rows, m.Columns, v.Rows, v.Columns))
+		}
+	}
+	if unexpected {
+		for k := range parameters {
+			if _, ok := dest[k]; !ok {
+				pe.Unexpected = append(pe.Unexpected, k)
+			}
+		}
+	}
+	if len(pe.Missing)+len(pe.Unexpected)+len(pe.Mismatched) == 0 {
+		return nil
+	}
+	sort.Strings(pe.Missing)
+	sort.Strings(pe.Unexpected)
+	sort.Strings(pe.Mismatched)
+	return pe
+}
+
+// LoadParameters copies weights of parameters into dest. Strict mode copies nothing unless
+// every parameter of dest is given with the same shape and storage type and nothing else is given.
+// Non strict mode copies all compatible parameters and reports skipped ones in *ParameterError,
+// it is meant for partial loading in transfer learning.
+func LoadParameters(dest, parameters map[string]*Matrix, strict bool) (loaded int, e error) {
+	return loadParameters(dest, parameters, strict, true)
+}
+
+func loadParameters(dest, parameters map[string]*Matrix, strict, unexpected bool) (loaded int, e error) {
+	pe := checkParameters(dest, parameters, unexpected)
+	if pe != nil && strict {
+		return 0, pe
+	}
+	for k, v := range dest {
+		m, ok := parameters[k]
+		if !ok || m.Rows != v.Rows || m.Columns != v.Columns || storage(m) != storage(v) {
+			continue
+		}
+		if len(m.W) == len(v.W) && len(m.Half) == len(v.Half) {
 			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
+			copy(v.Half, m.Half)
+			loaded++
 		}
 	}
-	return nil
+	if pe != nil {
+		return loaded, pe
+	}
+	return loaded, nil
+}
+
+// SetParameters strictly loads parameters into dest, see LoadParameters
+func SetParameters(dest, parameters map[string]*Matrix) error {
+	_, e := loadParameters(dest, parameters, true, true)
+	return e
+}
+
+// setParameters is SetParameters of cell, parameters are usually taken from whole model
+// so names absent in dest are not reported as unexpected
+func setParameters(dest, parameters map[string]*Matrix) error {
+	_, e := loadParameters(dest, parameters, true, false)
+	return e
 }
 
 func Sigmoid(shift, x float32) float32 {
@@ -703,3 +703,63 @@ func TestAutoencoder(t *testing.T) {
 			}*/
 	})
 }
+
+func TestSetParametersValidation(t *testing.T) {
+	gru := MakeOutputlessGRU(3, 4)
+	parameters := gru.GetParameters("Encoder")
+	saved := make(map[string]*Matrix)
+	for k, v := range parameters {
+		saved[k] = RandMat(v.Rows, v.Columns)
+	}
+	saved["Encoder_Wz"] = RandMat(4, 5)
+	delete(saved, "Encoder_Uz")
+	saved["Encoder_Extra"] = RandMat(1, 1)
+	saved["Decoder_Wz"] = RandMat(1, 1)
+
+	e := gru.SetParameters("Encoder", saved)
+	pe, ok := e.(*ParameterError)
+	if !ok {
+		t.Fatalf("ParameterError expected but %v returned", e)
+	}
+	if len(pe.Missing) != 1 || pe.Missing[0] != "Encoder_Uz" ||
+		len(pe.Unexpected) != 0 || len(pe.Mismatched) != 1 {
+		t.Fatalf("wrong report %v", pe)
+	}
+	if gru.Wr.W[0] == saved["Encoder_Wr"].W[0] {
+		t.Fatal("strict loading must not copy anything on error")
+	}
+	e = SetParameters(parameters, saved)
+	if pe, ok = e.(*ParameterError); !ok || len(pe.Unexpected) != 2 ||
+		pe.Unexpected[0] != "Decoder_Wz" || pe.Unexpected[1] != "Encoder_Extra" {
+		t.Fatalf("top level loading must report unexpected parameters but %v returned", e)
+	}
+
+	// parameters of nested layer share cell namespace prefix
+	model := gru.GetParameters("Encoder")
+	for k, v := range MakeLinear(4, 2, true).GetParameters("Encoder_Out") {
+		model[k] = v
+	}
+	if e := MakeOutputlessGRU(3, 4).SetParameters("Encoder", model); e != nil {
+		t.Fatal(e)
+	}
+
+	// half precision source is reported as dtype mismatch
+	compressed := MakeOutputlessGRU(3, 4).GetParameters("Encoder")
+	compressed["Encoder_Wz"].Compress(Float16)
+	e = MakeOutputlessGRU(3, 4).SetParameters("Encoder", compressed)
+	if pe, ok = e.(*ParameterError); !ok || len(pe.Mismatched) != 1 ||
+		pe.Mismatched[0] != "Encoder_Wz is float16 but float32 expected" {
+		t.Fatalf("dtype mismatch must be reported but %v returned", e)
+	}
+
+	loaded, e := LoadParameters(parameters, saved, false)
+	if e == nil {
+		t.Fatal("skipped parameters must be reported")
+	}
+	if loaded != len(parameters)-2 {
+		t.Fatalf("%d parameters loaded, %d expected", loaded, len(parameters)-2)
+	}
+	if gru.Wr.W[0] != saved["Encoder_Wr"].W[0] {
+		t.Fatal("compatible parameter is not loaded")
+	}
+}
@@ -3,6 +3,9 @@ package gortex
 import (
 	"fmt"
 	"math"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
 
 	"github.com/vseledkin/gortex/assembler"
@@ -11,25 +14,51 @@ import (
 
 const epsilon = 1e-9
 
+// layer normalization needs larger epsilon for stability of nearly constant vectors
+const layerNormEpsilon = 1e-5
+
 var mux sync.Mutex
 
 type Graph struct {
 	NeedsBackprop bool
 	Print         bool
+	// Trace is called after every backprop function with name of the op it belongs to
+	Trace func(op string)
 
 	// this will store a list of functions that perform backprop,
 	// in their forward pass order. So in backprop we will go
 	// backwards and evoke each one
 	backprop []func()
+	// per sequence dropout masks, see RecurrentDropout
+	masks map[maskKey]*Matrix
+	// multiplied sees weights of every product m1 * m2 and kernel of every convolution made,
+	// LSUV collects layer outputs with it
+	multiplied func(weights, out *Matrix)
 }
 
 func (g *Graph) Backward() {
 	for i := len(g.backprop) - 1; i >= 0; i-- {
 		g.backprop[i]()
+		if g.Trace != nil {
+			g.Trace(opName(g.backprop[i]))
+		}
 	}
 }
 
+// opName turns backprop closure name like gortex.(*Graph).Mul.func1 into Mul
+func opName(f func()) string {
+	name := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimPrefix(name, "gortex.")
+	name = strings.TrimPrefix(name, "(*Graph).")
+	if i := strings.Index(name, ".func"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
 func (g *Graph) InstanceNormalization(m *Matrix) *Matrix {
+	m = g.dense(m)
 	mean, variance := Moments(m)
 	stdDev := assembler.Sqrt(variance)
 	out := m.SameAs()
@@ -67,6 +96,43 @@ func (g *Graph) InstanceNormalization(m *Matrix) *Matrix {
 	return out
 }
 
+// LayerNorm normalizes vector to zero mean and unit variance then scales by gain and shifts by bias
+// https://arxiv.org/abs/1607.06450
+func (g *Graph) LayerNorm(m, gain, bias *Matrix) *Matrix {
+	m, gain, bias = g.dense(m), g.dense(gain), g.dense(bias)
+	N := float32(len(m.W))
+	mean := assembler.Sum(m.W) / N
+	var variance float32
+	for _, v := range m.W {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= N
+	inv := 1 / assembler.Sqrt(variance+layerNormEpsilon)
+	normalized := make([]float32, len(m.W))
+	out := m.SameAs()
+	for i, v := range m.W {
+		normalized[i] = (v - mean) * inv
+		out.W[i] = gain.W[i]*normalized[i] + bias.W[i]
+	}
+	if g.NeedsBackprop {
+		g.backprop = append(g.backprop, func() {
+			// dx = inv/N * (N*dn - sum(dn) - n*sum(dn*n)) where dn is gradient of normalized vector
+			var sum, sumNormalized float32
+			for i := range out.DW {
+				dn := out.DW[i] * gain.W[i]
+				sum += dn
+				sumNormalized += dn * normalized[i]
+				gain.DW[i] += out.DW[i] * normalized[i]
+				bias.DW[i] += out.DW[i]
+			}
+			for i := range m.DW {
+				m.DW[i] += inv / N * (N*out.DW[i]*gain.W[i] - sum - normalized[i]*sumNormalized)
+			}
+		})
+	}
+	return out
+}
+
 func (g *Graph) Tanh(m *Matrix, messages ...string) *Matrix {
 	// tanh nonlinearity
 	out := m.SameAs()
@@ -95,10 +161,19 @@ func (g *Graph) Lookup(lt *Matrix, i int) *Matrix {
 	// pickup rows as embeddings for speed so lt Matrix is treated as column major
 	out := Mat(lt.Rows, 1)
 	offset := i * lt.Rows
+	if lt.Half != nil {
+		if g.NeedsBackprop {
+			panic("compressed lookup table can not be trained, decompress it first")
+		}
+		widen(out.W, lt.Half[offset:offset+lt.Rows], lt.HalfType)
+		return out
+	}
 	// we can point to region in slice instead of copy
 	out.W = lt.W[offset: offset+lt.Rows]
 
 	if g.NeedsBackprop {
+		// remember column for sparse optimizer update
+		lt.Touch(i)
 		g.backprop = append(g.backprop, func() {
 			// gradient landing
 			assembler.Sxpy(out.DW, lt.DW[offset:offset+lt.Rows])
@@ -149,6 +224,7 @@ func (g *Graph) Sigmoid(m *Matrix) *Matrix {
 }
 
 func (g *Graph) Add(m1, m2 *Matrix, messages ...string) *Matrix {
+	m1, m2 = g.dense(m1), g.dense(m2)
 	l1 := len(m1.W)
 	l2 := len(m2.W)
 	if l1 != l2 {
@@ -176,6 +252,7 @@ func (g *Graph) Add(m1, m2 *Matrix, messages ...string) *Matrix {
 }
 
 func (g *Graph) Sub(m1, m2 *Matrix) *Matrix {
+	m1, m2 = g.dense(m1), g.dense(m2)
 	l1 := len(m1.W)
 	l2 := len(m2.W)
 	if l1 != l2 {
@@ -203,10 +280,25 @@ func (g *Graph) mulv(m1, m2 *Matrix) *Matrix {
 
 	out := Mat(m1.Rows, 1)
 
+	if m1.Half != nil {
+		if g.NeedsBackprop {
+			panic("compressed matrix can not be trained, decompress it first")
+		}
+		// widen row by row on the fly
+		row := make([]float32, m1.Columns)
+		for i := 0; i < m1.Rows; i++ {
+			widen(row, m1.Half[m1.Columns*i:m1.Columns*i+m1.Columns], m1.HalfType)
+			out.W[i] = assembler.Sdot(row, m2.W)
+		}
+		return out
+	}
 	for i := 0; i < m1.Rows; i++ { // loop over rows of m1
 		out.W[i] = assembler.Sdot(m1.W[m1.Columns*i:m1.Columns*i+m1.Columns], m2.W)
 	}
 	if g.NeedsBackprop {
+		// lookup tables tied with weights get dense gradient
+		m1.TouchAll()
+		m2.TouchAll()
 		g.backprop = append(g.backprop, func() {
 			for i := 0; i < m1.Rows; i++ { // loop over rows of m1
 				assembler.Saxpy(out.DW[i], m2.W, m1.DW[m1.Columns*i:m1.Columns*i+m1.Columns])
@@ -218,6 +310,7 @@ func (g *Graph) mulv(m1, m2 *Matrix) *Matrix {
 }
 
 func (g *Graph) PackColumnVectors(m1 []*Matrix) *Matrix {
+	m1 = g.denseAll(m1)
 	columns := len(m1)
 	rows := m1[0].Rows
 
@@ -239,6 +332,39 @@ func (g *Graph) PackColumnVectors(m1 []*Matrix) *Matrix {
 	return out
 }
 
+// UnpackColumnVectors splits matrix into its columns, it is inverse of PackColumnVectors
+func (g *Graph) UnpackColumnVectors(m *Matrix) []*Matrix {
+	m = g.dense(m)
+	out := make([]*Matrix, m.Columns)
+	for c := range out {
+		out[c] = Mat(m.Rows, 1)
+		for r := 0; r < m.Rows; r++ {
+			out[c].W[r] = m.W[m.Columns*r+c]
+		}
+	}
+	if g.NeedsBackprop {
+		g.backprop = append(g.backprop, func() {
+			for c := range out {
+				for r := 0; r < m.Rows; r++ {
+					m.DW[m.Columns*r+c] += out[c].DW[r]
+				}
+			}
+		})
+	}
+	return out
+}
+
+// transpose returns transposed copy of row major rows x columns matrix
+func transpose(w []float32, rows, columns int) []float32 {
+	t := make([]float32, len(w))
+	for r := 0; r < rows; r++ {
+		for c := 0; c < columns; c++ {
+			t[rows*c+r] = w[columns*r+c]
+		}
+	}
+	return t
+}
+
 func (g *Graph) Conv(m1 *Matrix, m2 *Matrix) *Matrix {
 	// multiply matrices m1 * m2
 	if m1.Rows != m2.Rows {
@@ -248,7 +374,11 @@ func (g *Graph) Conv(m1 *Matrix, m2 *Matrix) *Matrix {
 		panic(fmt.Errorf("conv dimensions misaligned m1.columns=%d must be equal m2.columns=%d", m1.Columns, m2.Columns))
 	}
 
-	return g.Sum(g.EMul(m1, m2))
+	out := g.Sum(g.EMul(m1, m2))
+	if g.multiplied != nil {
+		g.multiplied(m2, out)
+	}
+	return out
 }
 
 /*
@@ -290,30 +420,43 @@ func (g *Graph) Mul(m1, m2 *Matrix, messages ...string) *Matrix {
 		panic(fmt.Errorf("matmul dimensions misaligned m1.columns=%d must be equal m2.rows=%d", m1.Columns, m2.Rows))
 	}
 	if m2.Columns == 1 { // use highly optimized special case when m2 is vector
-		return g.mulv(m1, m2)
+		out := g.mulv(m1, m2)
+		if g.multiplied != nil {
+			g.multiplied(m1, out)
+		}
+		return out
 	}
+	weights := m1
+	m1, m2 = g.dense(m1), g.dense(m2)
 	out := Mat(m1.Rows, m2.Columns)
+	// transposed m2 has contiguous columns so vectorized dot products can be used
+	m2t := transpose(m2.W, m2.Rows, m2.Columns)
 	for i := 0; i < m1.Rows; i++ { // loop over rows of m1
+		row := m1.W[m1.Columns*i : m1.Columns*i+m1.Columns]
 		for j := 0; j < m2.Columns; j++ { // loop over cols of m2
-			var dot float32
-			for k := 0; k < m1.Columns; k++ { // dot product loop
-				dot += m1.W[m1.Columns*i+k] * m2.W[m2.Columns*k+j]
-			}
-			out.W[m2.Columns*i+j] = dot
+			out.W[m2.Columns*i+j] = assembler.Sdot(row, m2t[m2.Rows*j:m2.Rows*j+m2.Rows])
 		}
 	}
 	if g.NeedsBackprop {
+		m1.TouchAll()
+		m2.TouchAll()
 		g.backprop = append(g.backprop, func() {
 			//if len(messages) > 0 {
 			//	fmt.Printf("%s Norm:%f GradientNorm:%f\n", messages[0], out.Norm(), out.NormGradient())
 			//}
+			m2tDW := make([]float32, len(m2t))
 			for i := 0; i < m1.Rows; i++ { // loop over rows of m1
+				row := m1.W[m1.Columns*i : m1.Columns*i+m1.Columns]
+				rowDW := m1.DW[m1.Columns*i : m1.Columns*i+m1.Columns]
 				for j := 0; j < m2.Columns; j++ { // loop over cols of m2
 					b := out.DW[m2.Columns*i+j]
-					for k := 0; k < m1.Columns; k++ { // dot product loop
-						m1.DW[m1.Columns*i+k] += m2.W[m2.Columns*k+j] * b
-						m2.DW[m2.Columns*k+j] += m1.W[m1.Columns*i+k] * b
-					}
+					assembler.Saxpy(b, m2t[m2.Rows*j:m2.Rows*j+m2.Rows], rowDW)
+					assembler.Saxpy(b, row, m2tDW[m2.Rows*j:m2.Rows*j+m2.Rows])
+				}
+			}
+			for k := 0; k < m2.Rows; k++ {
+				for j := 0; j < m2.Columns; j++ {
+					m2.DW[m2.Columns*k+j] += m2tDW[m2.Rows*j+k]
 				}
 			}
 			if len(messages) > 0 && g.Print {
@@ -322,10 +465,14 @@ func (g *Graph) Mul(m1, m2 *Matrix, messages ...string) *Matrix {
 			}
 		})
 	}
+	if g.multiplied != nil {
+		g.multiplied(weights, out)
+	}
 	return out
 }
 
 func (g *Graph) Attention(m []*Matrix, v *Matrix) *Matrix {
+	m, v = g.denseAll(m), g.dense(v)
 	// multiply transposed matrix and vector m * v
 	Height := len(m)
 	Width := m[0].Rows
@@ -358,6 +505,7 @@ func (g *Graph) Attention(m []*Matrix, v *Matrix) *Matrix {
 
 // Sum of weights of x
 func (g *Graph) Sum(x *Matrix) *Matrix {
+	x = g.dense(x)
 	out := Mat(1, 1)
 	out.W[0] = assembler.Sum(x.W)
 	if g.NeedsBackprop {
@@ -576,6 +724,7 @@ func (g *Graph) BipolarSelu(x *Matrix) *Matrix {
 
 // EMul elementwise matrix matrix multiplication
 func (g *Graph) EMul(m1, m2 *Matrix, messages ...string) *Matrix {
+	m1, m2 = g.dense(m1), g.dense(m2)
 	l1 := len(m1.W)
 	l2 := len(m2.W)
 	if l1 != l2 {
@@ -626,6 +775,7 @@ func (g *Graph) ReplicateScalar(m *Matrix, n int) *Matrix {
 
 // Concatenate two or more vectors
 func (g *Graph) Concat(m ...*Matrix) *Matrix {
+	m = g.denseAll(m)
 	L := len(m)
 	if L < 2 {
 		panic(fmt.Errorf("concat is for two or more vectors but %d vectors given", L))
@@ -0,0 +1,119 @@
+package gortex
+
+import (
+	"fmt"
+
+	"github.com/vseledkin/gortex/assembler"
+)
+
+// widen converts half precision weights to float32
+func widen(dst []float32, src []uint16, dtype DType) {
+	switch dtype {
+	case Float16:
+		assembler.F16toF32(dst, src)
+	case BFloat16:
+		assembler.BF16toF32(dst, src)
+	default:
+		panic(fmt.Errorf("%s is not half precision type", dtype))
+	}
+}
+
+// narrow converts float32 weights to half precision
+func narrow(dst []uint16, src []float32, dtype DType) {
+	switch dtype {
+	case Float16:
+		assembler.F32toF16(dst, src)
+	case BFloat16:
+		assembler.F32toBF16(dst, src)
+	default:
+		panic(fmt.Errorf("%s is not half precision type", dtype))
+	}
+}
+
+// IsCompressed reports if matrix keeps weights in half precision
+func (m *Matrix) IsCompressed() bool {
+	return m.Half != nil
+}
+
+// weights returns float32 weights widening compressed ones into new slice
+func (m *Matrix) weights() []float32 {
+	if m.Half == nil {
+		return m.W
+	}
+	w := make([]float32, len(m.Half))
+	widen(w, m.Half, m.HalfType)
+	return w
+}
+
+// dense returns matrix with float32 weights, compressed one is widened into temporary copy,
+// graph operations take their inputs through it so compressed models run anywhere but can not be trained
+func (g *Graph) dense(m *Matrix) *Matrix {
+	if m.Half == nil {
+		return m
+	}
+	if g.NeedsBackprop {
+		panic(fmt.Errorf("compressed %s matrix %dx%d can not be trained, decompress it first", m.HalfType, m.Rows, m.Columns))
+	}
+	return &Matrix{Rows: m.Rows, Columns: m.Columns, W: m.weights()}
+}
+
+// denseAll is dense of every matrix, slice is copied only if some matrix is compressed
+func (g *Graph) denseAll(ms []*Matrix) []*Matrix {
+	for i, m := range ms {
+		if m.Half != nil {
+			ms = append([]*Matrix(nil), ms...)
+			for j := i; j < len(ms); j++ {
+				ms[j] = g.dense(ms[j])
+			}
+			break
+		}
+	}
+	return ms
+}
+
+// Compress replaces float32 weights by Float16 or BFloat16 ones halving memory,
+// compressed matrix is inference only, Graph.Mul by vector and Graph.Lookup widen its weights on the fly,
+// other operations widen whole matrix
+func (m *Matrix) Compress(dtype DType) {
+	if dtype != Float16 && dtype != BFloat16 {
+		panic(fmt.Errorf("can not compress matrix to %s", dtype))
+	}
+	if m.Half != nil {
+		if m.HalfType == dtype {
+			return
+		}
+		m.Decompress()
+	}
+	m.Half = make([]uint16, len(m.W))
+	narrow(m.Half, m.W, dtype)
+	m.HalfType = dtype
+	m.W, m.DW = nil, nil
+}
+
+// Decompress widens compressed weights back to float32 and allocates gradients
+func (m *Matrix) Decompress() {
+	if m.Half == nil {
+		return
+	}
+	m.W = m.weights()
+	m.DW = make([]float32, len(m.W))
+	m.Half = nil
+	m.HalfType = Float32
+}
+
+// CompressModel compresses weight matrices of model, vectors (biases, initial states) stay float32
+// because only matrix by vector multiplication and lookup use compressed weights without widening them
+func CompressModel(model map[string]*Matrix, dtype DType) {
+	for _, m := range model {
+		if m.Columns > 1 {
+			m.Compress(dtype)
+		}
+	}
+}
+
+// DecompressModel widens all compressed matrices of model
+func DecompressModel(model map[string]*Matrix) {
+	for _, m := range model {
+		m.Decompress()
+	}
+}
@@ -0,0 +1,194 @@
+package gortex
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// runLSTM runs lstm over embedded sequence and returns outputs of all steps
+func runLSTM(lstm *LSTM, embeddings *Matrix, sequence []int) []float32 {
+	G := &Graph{}
+	h, c := Mat(lstm.Uf.Rows, 1), Mat(lstm.Uf.Rows, 1)
+	var outputs []float32
+	for _, i := range sequence {
+		var y *Matrix
+		h, c, y = lstm.Step(G, G.Lookup(embeddings, i), h, c)
+		outputs = append(outputs, y.W...)
+	}
+	return outputs
+}
+
+func maxAbsDiff(a, b []float32) float64 {
+	var max float64
+	for i := range a {
+		if d := math.Abs(float64(a[i] - b[i])); d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+func TestHalfInference(t *testing.T) {
+	lstm := MakeLSTM(16, 32, 8)
+	embeddings := RandMat(16, 50)
+	sequence := []int{3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5, 8, 9, 7, 9}
+	expected := runLSTM(lstm, embeddings, sequence)
+
+	for _, test := range []struct {
+		dtype     DType
+		tolerance float64
+	}{{Float16, 5e-3}, {BFloat16, 5e-2}} {
+		model := lstm.GetParameters("LSTM")
+		model["Embeddings"] = embeddings
+		var buffer bytes.Buffer
+		if e := WriteModelBinaryAs(&buffer, model, test.dtype); e != nil {
+			t.Fatal(e)
+		}
+		var full bytes.Buffer
+		if e := WriteModelBinary(&full, model); e != nil {
+			t.Fatal(e)
+		}
+		if buffer.Len() >= full.Len()*3/5 {
+			t.Fatalf("%s model takes %d bytes, float32 one %d", test.dtype, buffer.Len(), full.Len())
+		}
+
+		// widened on load
+		loaded, e := ReadModelBinary(bytes.NewReader(buffer.Bytes()))
+		if e != nil {
+			t.Fatal(e)
+		}
+		widenedEmbeddings := loaded["Embeddings"]
+		delete(loaded, "Embeddings")
+		widened := MakeLSTM(16, 32, 8)
+		if e = widened.SetParameters("LSTM", loaded); e != nil {
+			t.Fatal(e)
+		}
+		actual := runLSTM(widened, widenedEmbeddings, sequence)
+		if d := maxAbsDiff(expected, actual); d > test.tolerance {
+			t.Fatalf("%s widened model output differs by %f", test.dtype, d)
+		}
+
+		// widened on the fly
+		compact, e := ReadModelBinaryCompact(bytes.NewReader(buffer.Bytes()))
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !compact["LSTM_Wf"].IsCompressed() || !compact["Embeddings"].IsCompressed() {
+			t.Fatal("compact model must keep weights compressed")
+		}
+		compressed := MakeLSTM(16, 32, 8)
+		for name, m := range compressed.GetParameters("LSTM") {
+			*m = *compact[name]
+		}
+		onTheFly := runLSTM(compressed, compact["Embeddings"], sequence)
+		for i := range actual {
+			if actual[i] != onTheFly[i] {
+				t.Fatalf("%s on the fly output %d is %f but widened %f", test.dtype, i, onTheFly[i], actual[i])
+			}
+		}
+	}
+}
+
+func TestCompressModel(t *testing.T) {
+	lstm := MakeLSTM(10, 20, 5)
+	embeddings := RandMat(10, 30)
+	sequence := []int{0, 29, 7, 7, 13}
+	expected := runLSTM(lstm, embeddings, sequence)
+	model := lstm.GetParameters("LSTM")
+	model["Embeddings"] = embeddings
+	CompressModel(model, Float16)
+	if lstm.Bf.IsCompressed() || !lstm.Wf.IsCompressed() || lstm.Wf.W != nil {
+		t.Fatal("only weight matrices must be compressed")
+	}
+	if d := maxAbsDiff(expected, runLSTM(lstm, embeddings, sequence)); d > 5e-3 {
+		t.Fatalf("compressed model output differs by %f", d)
+	}
+
+	// compressed matrices are saved and mapped as they are
+	name := filepath.Join(t.TempDir(), "model")
+	if e := SaveModel(name, model); e != nil {
+		t.Fatal(e)
+	}
+	mm, e := MapModel(name, true)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer mm.Close()
+	if mm.Parameters["LSTM_Wf"].HalfType != Float16 || mm.Parameters["LSTM_Bf"].IsCompressed() {
+		t.Fatal("mapped model must keep storage types")
+	}
+	for i, h := range lstm.Wf.Half {
+		if mm.Parameters["LSTM_Wf"].Half[i] != h {
+			t.Fatalf("mapped weight %d mismatch", i)
+		}
+	}
+	MakeTrainable(mm.Parameters)
+	if mm.Parameters["LSTM_Wf"].IsCompressed() || len(mm.Parameters["LSTM_Wf"].DW) != 200 {
+		t.Fatal("trainable model must be decompressed")
+	}
+
+	DecompressModel(model)
+	if lstm.Wf.IsCompressed() || len(lstm.Wf.W) != 200 || len(lstm.Wf.DW) != 200 {
+		t.Fatal("decompressed matrix must have weights and gradients")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("training compressed matrix must panic")
+		}
+	}()
+	lstm.Wf.Compress(BFloat16)
+	G := &Graph{NeedsBackprop: true}
+	G.Mul(lstm.Wf, RandMat(10, 1))
+}
+
+func TestCompactModelForward(t *testing.T) {
+	s := MakeSequential().
+		Add("conv", MakeDilatedTemporalConvolution(6, []int{5, 4}, true)).
+		Add("sru", MakeSRU(4, 8, 8)).
+		Add("qrnn", MakeQRNN(8, 6, 3)).
+		Add("norm", MakeLayerNormGRU(3, 5, 2))
+	x := make([]*Matrix, 7)
+	for i := range x {
+		x[i] = RandMat(6, 1)
+	}
+	var buffer bytes.Buffer
+	if e := WriteModelBinaryAs(&buffer, s.GetParameters("model"), Float16); e != nil {
+		t.Fatal(e)
+	}
+	loaded, e := ReadModelBinary(bytes.NewReader(buffer.Bytes()))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = s.SetParameters("model", loaded); e != nil {
+		t.Fatal(e)
+	}
+	expected := s.ForwardSequence(&Graph{}, x)
+
+	// convolution kernels, sequence projections and gates run compressed
+	compact, e := ReadModelBinaryCompact(bytes.NewReader(buffer.Bytes()))
+	if e != nil {
+		t.Fatal(e)
+	}
+	for name, m := range s.GetParameters("model") {
+		*m = *compact[name]
+	}
+	if !compact["model_conv_layer0_kernel0"].IsCompressed() || !compact["model_sru_W"].IsCompressed() {
+		t.Fatal("compact model must keep weights compressed")
+	}
+	actual := s.ForwardSequence(&Graph{}, x)
+	for i := range expected {
+		for j := range expected[i].W {
+			if actual[i].W[j] != expected[i].W[j] {
+				t.Fatalf("compact model output %d differs from widened one", i)
+			}
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("training compact model must panic")
+		}
+	}()
+	s.ForwardSequence(&Graph{NeedsBackprop: true}, x)
+}
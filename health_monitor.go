@@ -0,0 +1,178 @@
+package gortex
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/vseledkin/gortex/assembler"
+)
+
+// ParameterHealth statistics of one model parameter collected by HealthMonitor
+type ParameterHealth struct {
+	WeightNorm   float32 // L2 norm of weights
+	GradientNorm float32 // L2 norm of gradient
+	UpdateRatio  float32 // L2 norm of last update divided by weights norm
+	DeadFraction float32 // fraction of units with exactly zero gradient, rows of weights or columns of lookup tables
+	NonFiniteW   int     // number of NaN/Inf weights
+	NonFiniteDW  int     // number of NaN/Inf gradients
+	Origin       string  // op whose backprop first made gradient non finite, needs Trace
+}
+
+// HealthMonitor wraps optimizer and checks weights and gradients before every update
+type HealthMonitor struct {
+	Inner      Stepper
+	SkipUpdate bool // skip update and drop gradients if anything is not finite
+	Health     map[string]*ParameterHealth
+	Skipped    int // number of skipped updates
+	origin     map[string]string
+	previous   map[string][]float32
+}
+
+func NewHealthMonitor(inner Stepper, skipUpdate bool) *HealthMonitor {
+	return &HealthMonitor{
+		Inner:      inner,
+		SkipUpdate: skipUpdate,
+		Health:     make(map[string]*ParameterHealth),
+		origin:     make(map[string]string),
+		previous:   make(map[string][]float32),
+	}
+}
+
+func countNonFinite(x []float32) (n int) {
+	for _, v := range x {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			n++
+		}
+	}
+	return
+}
+
+// Trace makes graph backward pass remember which op first produced non finite gradient of model parameters,
+// it checks all parameters after every op so use it for debugging only
+func (hm *HealthMonitor) Trace(g *Graph, model map[string]*Matrix) {
+	g.Trace = func(op string) {
+		for name, m := range model {
+			if _, ok := hm.origin[name]; !ok && countNonFinite(m.DW) > 0 {
+				hm.origin[name] = op
+			}
+		}
+	}
+}
+
+// deadFraction is fraction of units with exactly zero gradient, units are rows of weights
+// and columns of lookup tables
+func deadFraction(m *Matrix) float32 {
+	dead := 0
+	if m.IsLookupTable() {
+		for c := 0; c < m.Columns; c++ {
+			zero := true
+			for r := 0; r < m.Rows && zero; r++ {
+				zero = m.DW[r*m.Columns+c] == 0
+			}
+			if zero {
+				dead++
+			}
+		}
+		return float32(dead) / float32(m.Columns)
+	}
+	for r := 0; r < m.Rows; r++ {
+		if assembler.L1(m.DW[r*m.Columns:(r+1)*m.Columns]) == 0 {
+			dead++
+		}
+	}
+	return float32(dead) / float32(m.Rows)
+}
+
+// Check collects parameter statistics, call it after Backward,
+// returns error naming parameters with NaN or Inf weights or gradients
+func (hm *HealthMonitor) Check(model map[string]*Matrix) error {
+	var problems []string
+	for name, m := range model {
+		h, ok := hm.Health[name]
+		if !ok {
+			h = new(ParameterHealth)
+			hm.Health[name] = h
+		}
+		w := m.weights()
+		h.NonFiniteW = countNonFinite(w)
+		h.Origin = hm.origin[name]
+		h.WeightNorm = assembler.L2(w)
+		h.NonFiniteDW, h.GradientNorm, h.DeadFraction = 0, 0, 0
+		if len(m.DW) > 0 { // mapped and compressed models have no gradients
+			h.NonFiniteDW = countNonFinite(m.DW)
+			h.GradientNorm = assembler.L2(m.DW)
+			h.DeadFraction = deadFraction(m)
+		}
+		if h.NonFiniteW > 0 {
+			problems = append(problems, fmt.Sprintf("%s has %d non finite weights", name, h.NonFiniteW))
+		}
+		if h.NonFiniteDW > 0 {
+			problem := fmt.Sprintf("%s has %d non finite gradients", name, h.NonFiniteDW)
+			if len(h.Origin) > 0 {
+				problem += " produced by " + h.Origin
+			}
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("model is not healthy: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (hm *HealthMonitor) Step(model map[string]*Matrix) OpRet {
+	defer func() {
+		hm.origin = make(map[string]string)
+	}()
+	if e := hm.Check(model); e != nil {
+		log.Printf("WARNING: %v", e)
+		if hm.SkipUpdate {
+			for _, m := range model {
+				assembler.Sclean(m.DW)
+				m.ResetTouched()
+			}
+			hm.Skipped++
+			return OpRet{Skipped: true}
+		}
+	}
+	for name, m := range model {
+		previous, ok := hm.previous[name]
+		if !ok || len(previous) != m.Numel() {
+			previous = make([]float32, m.Numel())
+			hm.previous[name] = previous
+		}
+		copy(previous, m.W)
+	}
+	ret := hm.Inner.Step(model)
+	for name, m := range model {
+		previous := hm.previous[name]
+		// previous = W - previous
+		assembler.Saxplusbysetz(1, m.W, -1, previous, previous)
+		h := hm.Health[name]
+		h.WeightNorm = assembler.L2(m.W)
+		if h.WeightNorm > 0 {
+			h.UpdateRatio = assembler.L2(previous) / h.WeightNorm
+		}
+	}
+	return ret
+}
+
+// Report formats collected statistics one parameter per line
+func (hm *HealthMonitor) Report() string {
+	names := make([]string, 0, len(hm.Health))
+	for name := range hm.Health {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	report := ""
+	for _, name := range names {
+		h := hm.Health[name]
+		report += fmt.Sprintf("%s W:%f DW:%f update/W:%e dead:%.2f nonfinite W:%d DW:%d %s\n",
+			name, h.WeightNorm, h.GradientNorm, h.UpdateRatio, h.DeadFraction, h.NonFiniteW, h.NonFiniteDW, h.Origin)
+	}
+	return report
+}
@@ -0,0 +1,64 @@
+package gortex
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	W := RandXavierMat(3, 2)
+	b := Mat(3, 1)
+	model := map[string]*Matrix{"W": W, "b": b}
+	monitor := NewHealthMonitor(NewOptimizer(OpOp{Method: SGD, LearningRate: 0.1}), true)
+
+	// healthy step
+	x := Mat(2, 1)
+	x.W[0] = 1
+	G := &Graph{NeedsBackprop: true}
+	G.MSE(G.Add(G.Mul(W, x), b), Mat(3, 1))
+	G.Backward()
+	if ret := monitor.Step(model); ret.Skipped {
+		t.Fatal("healthy update must not be skipped")
+	}
+	if monitor.Health["W"].UpdateRatio <= 0 {
+		t.Fatalf("update ratio must be positive but %f", monitor.Health["W"].UpdateRatio)
+	}
+
+	// poison input and trace where gradient breaks
+	x.W[1] = float32(math.NaN())
+	G = &Graph{NeedsBackprop: true}
+	monitor.Trace(G, model)
+	G.MSE(G.Add(G.Mul(W, x), b), Mat(3, 1))
+	G.Backward()
+	before := W.CopyAs()
+	e := monitor.Check(model)
+	if e == nil || !strings.Contains(e.Error(), "W has 6 non finite gradients produced by mulv; b has 3 non finite gradients produced by Add") {
+		t.Fatalf("non finite gradient of W must be reported with origin but %v", e)
+	}
+	if ret := monitor.Step(model); !ret.Skipped || monitor.Skipped != 1 {
+		t.Fatal("unhealthy update must be skipped")
+	}
+	for i := range W.W {
+		if W.W[i] != before.W[i] || W.DW[i] != 0 {
+			t.Fatal("weights must stay and gradients must be dropped when update is skipped")
+		}
+	}
+	t.Log(monitor.Report())
+
+	// compressed weights have no gradients, units of lookup table are its columns
+	half := RandMat(2, 2)
+	half.W[3] = float32(math.Inf(1))
+	half.Compress(Float16)
+	table := RandMat(3, 4)
+	G = &Graph{NeedsBackprop: true}
+	G.Lookup(table, 1).DW[0] = 1
+	G.Backward()
+	monitor = NewHealthMonitor(NewOptimizer(OpOp{Method: SGD, LearningRate: 0.1}), false)
+	if e := monitor.Check(map[string]*Matrix{"half": half, "table": table}); e == nil || !strings.Contains(e.Error(), "half has 1 non finite weights") {
+		t.Fatalf("non finite compressed weight must be reported but %v", e)
+	}
+	if f := monitor.Health["table"].DeadFraction; f != 0.75 {
+		t.Fatalf("3 of 4 lookup table columns have no gradient but dead fraction is %f", f)
+	}
+}
@@ -0,0 +1,195 @@
+package gortex
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/vseledkin/gortex/assembler"
+)
+
+/*
+	Highway Networks https://arxiv.org/abs/1505.00387
+	Rupesh Kumar Srivastava, Klaus Greff, Jürgen Schmidhuber
+
+	y = T(x)*H(x) + (1-T(x))*x, transform gate T decides how much of x is carried unchanged
+*/
+
+type Highway struct {
+	Wh *Matrix
+	Bh *Matrix
+
+	Wt *Matrix
+	Bt *Matrix // negative bias makes layer carry input at start of training
+
+	Activation string
+	activation Activation
+}
+
+// MakeHighway makes highway layer of given size, transform gate bias starts from -1 as paper suggests
+func MakeHighway(size int, activation string, options ...InitOption) *Highway {
+	a, e := ActivationByName(activation)
+	if e != nil {
+		panic(e)
+	}
+	in := NewInitialization(XavierNormal{}, XavierNormal{}, Constant{}, options...)
+	h := &Highway{Activation: activation, activation: a}
+	h.Wh = in.weights(size, size)
+	h.Bh = in.biases(size)
+	h.Wt = in.weights(size, size)
+	h.Bt = Mat(size, 1)
+	h.CarryGateTrick(-1)
+	return h
+}
+
+// CarryGateTrick sets transform gate bias, the more negative v the more input is carried
+func (h *Highway) CarryGateTrick(v float32) {
+	assembler.Sset(v, h.Bt.W)
+}
+
+func (h *Highway) GetParameters(namespace string) map[string]*Matrix {
+	return map[string]*Matrix{
+		namespace + "_Wh": h.Wh,
+		namespace + "_Bh": h.Bh,
+		namespace + "_Wt": h.Wt,
+		namespace + "_Bt": h.Bt,
+	}
+}
+
+func (h *Highway) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(h.GetParameters(namespace), parameters)
+}
+
+func (h *Highway) Forward(g *Graph, x *Matrix) *Matrix {
+	if h.activation == nil {
+		h.activation, _ = ActivationByName(h.Activation)
+	}
+	transformed := h.activation(g, g.Add(g.Mul(h.Wh, x), h.Bh))
+	t := g.Sigmoid(g.Add(g.Mul(h.Wt, x), h.Bt))
+	return g.Add(g.EMul(t, transformed), g.EMul(g.Sub(t.OnesAs(), t), x))
+}
+
+// Residual adds input of wrapped layer to its output, input is projected
+// when sizes differ, wrapped layer parameters are kept under namespace itself
+type Residual struct {
+	Wrapped    SequenceLayer
+	Projection *Linear // nil if input and output sizes are equal
+	layer      Layer   // nil if wrapped layer works with sequences only
+}
+
+// MakeResidual wraps any layer, cell or convolution adapted by AsSequenceLayer
+func MakeResidual(layer interface{}, x_size, out_size int, options ...InitOption) *Residual {
+	l, e := AsSequenceLayer(layer)
+	if e != nil {
+		panic(e)
+	}
+	r := &Residual{Wrapped: l}
+	r.layer, _ = AsLayer(layer)
+	if x_size != out_size {
+		r.Projection = MakeLinear(x_size, out_size, false, options...)
+	}
+	return r
+}
+
+func (r *Residual) GetParameters(namespace string) map[string]*Matrix {
+	p := r.Wrapped.GetParameters(namespace)
+	if r.Projection != nil {
+		for k, v := range r.Projection.GetParameters(namespace + "_residual") {
+			p[k] = v
+		}
+	}
+	return p
+}
+
+func (r *Residual) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(r.GetParameters(namespace), parameters)
+}
+
+func (r *Residual) shortcut(g *Graph, x, y *Matrix) *Matrix {
+	if r.Projection != nil {
+		x = r.Projection.Forward(g, x)
+	}
+	return g.Add(y, x)
+}
+
+// Forward is available only when wrapped layer is Layer
+func (r *Residual) Forward(g *Graph, x *Matrix) *Matrix {
+	if r.layer == nil {
+		panic(fmt.Errorf("%T works with sequences only", r.Wrapped))
+	}
+	return r.shortcut(g, x, r.layer.Forward(g, x))
+}
+
+func (r *Residual) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	return r.ForwardMasked(g, x, nil)
+}
+
+// ForwardMasked skips padding steps, wrapped layer must be MaskedSequenceLayer if mask is given
+func (r *Residual) ForwardMasked(g *Graph, x []*Matrix, mask []bool) []*Matrix {
+	var y []*Matrix
+	if mask == nil {
+		y = r.Wrapped.ForwardSequence(g, x)
+	} else if ml, ok := r.Wrapped.(MaskedSequenceLayer); ok {
+		y = ml.ForwardMasked(g, x, mask)
+	} else {
+		panic(fmt.Errorf("layer of type %T can not skip padding steps", r.Wrapped))
+	}
+	if len(y) != len(x) {
+		panic(fmt.Errorf("residual needs output of every step but %d of %d given", len(y), len(x)))
+	}
+	for t := range y {
+		if y[t] != nil {
+			y[t] = r.shortcut(g, x[t], y[t])
+		}
+	}
+	return y
+}
+
+// HighwayConfig makes Highway under Highway architecture name
+type HighwayConfig struct {
+	Namespace  string // parameters namespace, Highway if empty
+	Size       int
+	Activation string
+}
+
+// ResidualConfig wraps layer of registered architecture under Residual architecture name
+type ResidualConfig struct {
+	Namespace string // parameters namespace, Residual if empty
+	Layer     LayerConfig
+	Input     int
+	Output    int
+}
+
+func init() {
+	RegisterArchitecture("Highway", func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(HighwayConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("Highway config: %v", e)
+		}
+		if _, e := ActivationByName(c.Activation); e != nil {
+			return nil, nil, fmt.Errorf("Highway config: %v", e)
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = "Highway"
+		}
+		h := MakeHighway(c.Size, c.Activation)
+		return h, h.GetParameters(c.Namespace), nil
+	})
+	RegisterArchitecture("Residual", func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(ResidualConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("Residual config: %v", e)
+		}
+		model, e := buildLayer(c.Layer)
+		if e != nil {
+			return nil, nil, fmt.Errorf("Residual %v", e)
+		}
+		if _, e := AsSequenceLayer(model); e != nil {
+			return nil, nil, fmt.Errorf("Residual layer %s: %v", c.Layer.Name, e)
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = "Residual"
+		}
+		r := MakeResidual(model, c.Input, c.Output)
+		return r, r.GetParameters(c.Namespace), nil
+	})
+}
@@ -0,0 +1,83 @@
+package gortex
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestHighway(t *testing.T) {
+	h := MakeHighway(6, "Relu")
+	// closed transform gate carries input
+	h.CarryGateTrick(-30)
+	x := RandMat(6, 1)
+	y := h.Forward(&Graph{}, x)
+	for i := range x.W {
+		if math.Abs(float64(x.W[i]-y.W[i])) > 1e-6 {
+			t.Fatal("highway with closed transform gate must carry input")
+		}
+	}
+	h.CarryGateTrick(-1)
+
+	// highway between stacked cells
+	s := MakeStacked(MakeGRU(5, 8, 6), h, MakeLSTM(6, 8, 3))
+	in := []*Matrix{RandMat(5, 1), RandMat(5, 1), RandMat(5, 1)}
+	G := &Graph{NeedsBackprop: true}
+	out := s.ForwardSequence(G, in)
+	for i := range out[2].DW {
+		out[2].DW[i] = 1
+	}
+	G.Backward()
+	touched := false
+	for _, v := range h.Wt.DW {
+		touched = touched || v != 0
+	}
+	if !touched || len(out) != 3 || out[2].Rows != 3 {
+		t.Fatal("gradient does not reach highway layer")
+	}
+	if p := s.GetParameters("model"); p["model_layer1_Bt"] == nil {
+		t.Fatal("highway parameters are not namespaced")
+	}
+}
+
+func TestResidual(t *testing.T) {
+	x := []*Matrix{RandMat(4, 1), RandMat(4, 1), RandMat(4, 1), RandMat(4, 1)}
+
+	// projected shortcut over convolution changing dimension
+	dtc := MakeDilatedTemporalConvolution(4, []int{6, 6}, true)
+	r := MakeResidual(dtc, 4, dtc.OutputSize())
+	G := &Graph{NeedsBackprop: true}
+	y := r.ForwardSequence(G, x)
+	if len(y) != 4 || y[0].Rows != 6 {
+		t.Fatalf("wrong output %d x %d", len(y), y[0].Rows)
+	}
+	G.Backward()
+	p := r.GetParameters("res")
+	if p["res_residual_W"] == nil || len(p) != len(dtc.GetParameters("res"))+1 {
+		t.Fatal("projection must be kept next to wrapped layer parameters")
+	}
+
+	// identity shortcut over vector layer
+	mlp := MakeMLP([]int{4, 8, 4}, "Tanh", 0, false)
+	rl := MakeResidual(mlp, 4, 4)
+	expected := mlp.Forward(&Graph{}, x[0])
+	actual := rl.Forward(&Graph{}, x[0])
+	for i := range actual.W {
+		if actual.W[i] != expected.W[i]+x[0].W[i] {
+			t.Fatal("residual output must be sum of layer output and input")
+		}
+	}
+
+	// padding steps are skipped
+	rm := MakeResidual(MakeLSTM(4, 5, 4), 4, 4)
+	y = rm.ForwardMasked(&Graph{}, x, []bool{true, true, false, false})
+	if y[2] != nil || y[1] == nil {
+		t.Fatal("padding steps must have no output")
+	}
+
+	config, _ := json.Marshal(&CellConfig{Input: 4, Hidden: 5, Output: 3})
+	b, e := NewBundle("Residual", &ResidualConfig{Input: 4, Output: 3, Layer: LayerConfig{Name: "rnn", Architecture: "GRU", Config: config}})
+	if e != nil || b.Parameters["Residual_residual_W"] == nil || b.Parameters["Residual_Uz"] == nil {
+		t.Fatalf("Residual is not registered properly: %v", e)
+	}
+}
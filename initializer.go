@@ -0,0 +1,228 @@
+package gortex
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/vseledkin/gortex/assembler"
+)
+
+// Initializer fills matrix with initial values, weights are rows=fan out by columns=fan in
+type Initializer interface {
+	Initialize(m *Matrix)
+}
+
+// InitializerFunc adapts function to Initializer
+type InitializerFunc func(m *Matrix)
+
+func (f InitializerFunc) Initialize(m *Matrix) { f(m) }
+
+// gain returns 1 for unset gain
+func gain(g float32) float64 {
+	if g == 0 {
+		return 1
+	}
+	return float64(g)
+}
+
+func fillUniform(m *Matrix, limit float64) {
+	for i := range m.W {
+		m.W[i] = float32((2*rand.Float64() - 1) * limit)
+	}
+}
+
+func fillNormal(m *Matrix, mean, deviation float64) {
+	for i := range m.W {
+		m.W[i] = float32(rand.NormFloat64()*deviation + mean)
+	}
+}
+
+// XavierUniform is Glorot uniform initialization with variance gain^2 * 2 / (fan in + fan out)
+type XavierUniform struct{ Gain float32 }
+
+func (x XavierUniform) Initialize(m *Matrix) {
+	fillUniform(m, gain(x.Gain)*math.Sqrt(6/float64(m.Rows+m.Columns)))
+}
+
+// XavierNormal is Glorot normal initialization with variance gain^2 * 2 / (fan in + fan out), same as RandXavierMat
+type XavierNormal struct{ Gain float32 }
+
+func (x XavierNormal) Initialize(m *Matrix) {
+	fillNormal(m, 0, gain(x.Gain)*math.Sqrt(2/float64(m.Rows+m.Columns)))
+}
+
+// HeUniform is Kaiming uniform initialization with variance 2 / fan in suited for Relu
+type HeUniform struct{}
+
+func (HeUniform) Initialize(m *Matrix) {
+	fillUniform(m, math.Sqrt(6/float64(m.Columns)))
+}
+
+// HeNormal is Kaiming normal initialization with variance 2 / fan in suited for Relu
+type HeNormal struct{}
+
+func (HeNormal) Initialize(m *Matrix) {
+	fillNormal(m, 0, math.Sqrt(2/float64(m.Columns)))
+}
+
+// Normal initialization, same as RandMatMD
+type Normal struct{ Mean, Deviation float32 }
+
+func (n Normal) Initialize(m *Matrix) {
+	fillNormal(m, float64(n.Mean), float64(n.Deviation))
+}
+
+// TruncatedNormal redraws values further than two deviations from mean
+type TruncatedNormal struct{ Mean, Deviation float32 }
+
+func (n TruncatedNormal) Initialize(m *Matrix) {
+	for i := range m.W {
+		v := rand.NormFloat64()
+		for math.Abs(v) > 2 {
+			v = rand.NormFloat64()
+		}
+		m.W[i] = float32(v*float64(n.Deviation)) + n.Mean
+	}
+}
+
+// Constant fills matrix with Value, zero by default
+type Constant struct{ Value float32 }
+
+func (c Constant) Initialize(m *Matrix) {
+	assembler.Sset(c.Value, m.W)
+}
+
+// Identity puts Scale (1 if zero) on main diagonal, vectors are filled with Scale
+// as they are diagonals of element wise recurrence like in IndRNN
+type Identity struct{ Scale float32 }
+
+func (id Identity) Initialize(m *Matrix) {
+	scale := float32(gain(id.Scale))
+	if m.Columns == 1 {
+		assembler.Sset(scale, m.W)
+		return
+	}
+	assembler.Sset(0, m.W)
+	for i := 0; i < m.Rows && i < m.Columns; i++ {
+		m.W[m.Columns*i+i] = scale
+	}
+}
+
+// Orthogonal makes orthonormal rows or columns whichever are fewer scaled by Gain,
+// recurrent matrices made so neither explode nor vanish at start of training
+// Exact solutions to the nonlinear dynamics of learning in deep linear neural networks https://arxiv.org/abs/1312.6120
+type Orthogonal struct{ Gain float32 }
+
+func (o Orthogonal) Initialize(m *Matrix) {
+	// orthonormalize vectors of length n by modified Gram-Schmidt
+	n, k := m.Columns, m.Rows
+	if k > n {
+		n, k = k, n
+	}
+	vectors := make([][]float64, k)
+	for i := range vectors {
+		for {
+			v := make([]float64, n)
+			for j := range v {
+				v[j] = rand.NormFloat64()
+			}
+			for _, u := range vectors[:i] {
+				var dot float64
+				for j := range v {
+					dot += v[j] * u[j]
+				}
+				for j := range v {
+					v[j] -= dot * u[j]
+				}
+			}
+			var norm float64
+			for j := range v {
+				norm += v[j] * v[j]
+			}
+			if norm = math.Sqrt(norm); norm > 1e-6 {
+				for j := range v {
+					v[j] /= norm
+				}
+				vectors[i] = v
+				break
+			}
+		}
+	}
+	g := gain(o.Gain)
+	for i, v := range vectors {
+		for j := range v {
+			if m.Rows <= m.Columns { // rows are orthonormal
+				m.W[m.Columns*i+j] = float32(g * v[j])
+			} else { // columns are orthonormal
+				m.W[m.Columns*j+i] = float32(g * v[j])
+			}
+		}
+	}
+}
+
+// lsuvMat keeps LSUVMat default of some cells
+var lsuvMat = InitializerFunc(func(m *Matrix) { copy(m.W, LSUVMat(m.Rows, m.Columns).W) })
+
+// Initialization tells constructors how to fill weights, every Make* constructor
+// accepts InitOption to override its default initializers
+type Initialization struct {
+	Weights   Initializer // input and output projections
+	Recurrent Initializer // hidden to hidden matrices and element wise recurrent weights
+	Biases    Initializer // biases and other vectors like peepholes, normalization gains are always ones
+}
+
+type InitOption func(in *Initialization)
+
+// WithWeights sets initializer of input and output projections
+func WithWeights(i Initializer) InitOption {
+	return func(in *Initialization) { in.Weights = i }
+}
+
+// WithRecurrent sets initializer of hidden to hidden weights
+func WithRecurrent(i Initializer) InitOption {
+	return func(in *Initialization) { in.Recurrent = i }
+}
+
+// WithBiases sets initializer of biases
+func WithBiases(i Initializer) InitOption {
+	return func(in *Initialization) { in.Biases = i }
+}
+
+// WithAll sets initializer of all parameters
+func WithAll(i Initializer) InitOption {
+	return func(in *Initialization) { in.Weights, in.Recurrent, in.Biases = i, i, i }
+}
+
+// NewInitialization applies options to constructor defaults, models outside of the package
+// use it to take the same options as constructors of the package do
+func NewInitialization(weights, recurrent, biases Initializer, options ...InitOption) *Initialization {
+	in := &Initialization{Weights: weights, Recurrent: recurrent, Biases: biases}
+	for _, option := range options {
+		option(in)
+	}
+	return in
+}
+
+// xavier is default initialization of most cells
+func xavier(options []InitOption) *Initialization {
+	return NewInitialization(XavierNormal{}, XavierNormal{}, XavierNormal{}, options...)
+}
+
+// InitMat makes rows x columns matrix filled by initializer
+func InitMat(i Initializer, rows, columns int) *Matrix {
+	m := Mat(rows, columns)
+	i.Initialize(m)
+	return m
+}
+
+func (in *Initialization) weights(rows, columns int) *Matrix {
+	return InitMat(in.Weights, rows, columns)
+}
+
+func (in *Initialization) recurrent(rows, columns int) *Matrix {
+	return InitMat(in.Recurrent, rows, columns)
+}
+
+func (in *Initialization) biases(rows int) *Matrix {
+	return InitMat(in.Biases, rows, 1)
+}
@@ -0,0 +1,121 @@
+package gortex
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInitializers(t *testing.T) {
+	variances := []struct {
+		name     string
+		i        Initializer
+		variance float64
+	}{
+		{"XavierNormal", XavierNormal{}, 2.0 / 500},
+		{"XavierUniform", XavierUniform{}, 2.0 / 500},
+		{"HeNormal", HeNormal{}, 2.0 / 300},
+		{"HeUniform", HeUniform{}, 2.0 / 300},
+		{"Normal", Normal{0, 0.1}, 0.01},
+	}
+	for _, v := range variances {
+		m := Mat(200, 300)
+		v.i.Initialize(m)
+		_, variance := Moments(m)
+		if math.Abs(float64(variance)-v.variance) > 0.05*v.variance {
+			t.Fatalf("%s variance is %f instead of %f", v.name, variance, v.variance)
+		}
+	}
+
+	m := Mat(50, 40)
+	TruncatedNormal{1, 0.5}.Initialize(m)
+	for _, v := range m.W {
+		if v < 0 || v > 2 {
+			t.Fatalf("truncated normal value %f is beyond two deviations", v)
+		}
+	}
+	Identity{}.Initialize(m)
+	Constant{3}.Initialize(Mat(2, 2))
+	if m.Get(7, 7) != 1 || m.Get(7, 8) != 0 || m.Get(45, 39) != 0 {
+		t.Fatal("wrong identity")
+	}
+
+	// orthonormal rows of wide and columns of tall matrices
+	for _, shape := range [][2]int{{30, 50}, {50, 30}, {40, 40}} {
+		m := Mat(shape[0], shape[1])
+		Orthogonal{}.Initialize(m)
+		k, n := shape[0], shape[1]
+		at := func(i, j int) float32 { return m.Get(i, j) }
+		if k > n {
+			k, n = n, k
+			at = func(i, j int) float32 { return m.Get(j, i) }
+		}
+		for a := 0; a < k; a++ {
+			for b := 0; b < k; b++ {
+				var dot float32
+				for j := 0; j < n; j++ {
+					dot += at(a, j) * at(b, j)
+				}
+				if expected := float32(map[bool]int{true: 1}[a == b]); Abs(dot-expected) > 1e-4 {
+					t.Fatalf("%v matrix is not orthogonal %d.%d=%f", shape, a, b, dot)
+				}
+			}
+		}
+	}
+}
+
+func TestInitOptions(t *testing.T) {
+	lstm := MakeLSTM(5, 6, 3, WithRecurrent(Identity{}), WithBiases(Constant{0.5}))
+	if lstm.Uf.Get(2, 2) != 1 || lstm.Uc.Get(2, 3) != 0 || lstm.Bo.W[4] != 0.5 {
+		t.Fatal("options are not applied to LSTM")
+	}
+	if lstm.Wf.Get(2, 2) == 1 {
+		t.Fatal("recurrent option must not change input weights")
+	}
+	ind := MakeIndRNN(2, 5, 3, 6, 2, WithRecurrent(Identity{}))
+	for _, v := range ind.U[1][2].W {
+		if v != 1 {
+			t.Fatal("IndRNN recurrent weights must be ones")
+		}
+	}
+	mlp := MakeMLP([]int{4, 5, 2}, "Relu", 0, false, WithAll(Constant{2}))
+	if mlp.Layers[1].W.W[3] != 2 || mlp.Layers[0].B.W[1] != 2 {
+		t.Fatal("options are not passed to MLP layers")
+	}
+	if dtc := MakeDilatedTemporalConvolution(4, []int{3}, true, WithBiases(Constant{1})); dtc.ConvBiases[0].W[2] != 1 {
+		t.Fatal("options are not applied to convolution")
+	}
+}
+
+func TestLSUV(t *testing.T) {
+	mlp := MakeMLP([]int{10, 40, 40, 5}, "Tanh", 0, false, WithWeights(Normal{0, 3}))
+	samples := make([][]*Matrix, 20)
+	for i := range samples {
+		samples[i] = []*Matrix{RandMat(10, 1), RandMat(10, 1)}
+	}
+	ret, e := LSUV(mlp, "mlp", samples, &LSUVOp{Orthogonal: true})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(ret.Order) != 3 || ret.Order[0] != "mlp_layer0_W" || ret.Order[2] != "mlp_layer2_W" {
+		t.Fatalf("weights must be initialized in order of use but %v", ret.Order)
+	}
+	for name, variance := range ret.Variances {
+		if Abs(variance-1) > 0.1 {
+			t.Fatalf("%s output variance is %f", name, variance)
+		}
+	}
+
+	// recurrent weights of cells are scaled too
+	ret, e = LSUV(MakeStacked(MakeGRU(10, 8, 8), MakeLSTM(8, 8, 3)), "rnn", samples, nil)
+	if e != nil || len(ret.Order) != 16 {
+		t.Fatalf("all weights of stacked cells must be initialized %v %v", ret, e)
+	}
+	// convolution kernels are scaled as well
+	ret, e = LSUV(MakeDilatedTemporalConvolution(10, []int{4, 4}, true), "dtc", samples, nil)
+	if e != nil || len(ret.Order) != 10 || len(ret.Skipped) != 0 || ret.Order[0] != "dtc_layer0_kernel0" {
+		t.Fatalf("kernels and gates of convolution must be initialized %v %v", ret, e)
+	}
+	if _, e := LSUV(mlp, "mlp", nil, nil); e == nil {
+		t.Fatal("LSUV without samples must fail")
+	}
+}
@@ -0,0 +1,99 @@
+package gortex
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// LanguageModel is token embedding lookup table, recurrent cell and output head,
+// kept in bundle with dictionary and tokenizer it is ready to run model of token sequences
+type LanguageModel struct {
+	Embedding *Matrix // lookup table, column is embedding of token
+	Cell      RecurrentCell
+	Head      *Linear // maps cell output to token logits, nil if cell output is logits itself
+}
+
+// MakeLanguageModel wraps any cell adapted by AsRecurrentCell, head is made only when
+// cell output size differs from vocabulary size, options are applied to embedding and head
+func MakeLanguageModel(vocabulary, embedding_size int, cell interface{}, options ...InitOption) *LanguageModel {
+	c, e := AsRecurrentCell(cell)
+	if e != nil {
+		panic(e)
+	}
+	in := NewInitialization(Normal{0, 1}, XavierNormal{}, Constant{}, options...)
+	lm := &LanguageModel{Embedding: in.weights(embedding_size, vocabulary), Cell: c}
+	// output size of cell is known only after it runs
+	_, y := c.StepState(&Graph{}, Mat(embedding_size, 1), c.InitialState())
+	if y.Rows != vocabulary {
+		lm.Head = MakeLinear(y.Rows, vocabulary, true, options...)
+	}
+	return lm
+}
+
+func (lm *LanguageModel) GetParameters(namespace string) map[string]*Matrix {
+	p := lm.Cell.GetParameters(namespace + "_Cell")
+	p[namespace+"_Embedding"] = lm.Embedding
+	if lm.Head != nil {
+		for k, v := range lm.Head.GetParameters(namespace + "_Head") {
+			p[k] = v
+		}
+	}
+	return p
+}
+
+func (lm *LanguageModel) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(lm.GetParameters(namespace), parameters)
+}
+
+func (lm *LanguageModel) InitialState() *State {
+	return lm.Cell.InitialState()
+}
+
+// Step consumes token and returns next state and logits of next token
+func (lm *LanguageModel) Step(g *Graph, token int, s *State) (next *State, logits *Matrix) {
+	next, logits = lm.Cell.StepState(g, g.Lookup(lm.Embedding, token), s)
+	if lm.Head != nil {
+		logits = lm.Head.Forward(g, logits)
+	}
+	return
+}
+
+// Forward returns logits of next token after every token of sequence starting from initial state
+func (lm *LanguageModel) Forward(g *Graph, tokens []int) []*Matrix {
+	logits := make([]*Matrix, len(tokens))
+	s := lm.InitialState()
+	for t, token := range tokens {
+		s, logits[t] = lm.Step(g, token, s)
+	}
+	return logits
+}
+
+// LanguageModelConfig makes LanguageModel under LanguageModel architecture name,
+// Cell is config of any registered cell which input size is Embedding
+type LanguageModelConfig struct {
+	Namespace  string // parameters namespace, LanguageModel if empty
+	Vocabulary int
+	Embedding  int
+	Cell       LayerConfig
+}
+
+func init() {
+	RegisterArchitecture("LanguageModel", func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(LanguageModelConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("LanguageModel config: %v", e)
+		}
+		if c.Vocabulary < 1 || c.Embedding < 1 {
+			return nil, nil, fmt.Errorf("LanguageModel config: vocabulary %d and embedding %d sizes must be positive", c.Vocabulary, c.Embedding)
+		}
+		cell, e := buildCell(c.Cell)
+		if e != nil {
+			return nil, nil, fmt.Errorf("LanguageModel %v", e)
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = "LanguageModel"
+		}
+		lm := MakeLanguageModel(c.Vocabulary, c.Embedding, cell)
+		return lm, lm.GetParameters(c.Namespace), nil
+	})
+}
@@ -0,0 +1,449 @@
+package gortex
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Parameterized is anything keeping trainable parameters under namespace
+type Parameterized interface {
+	GetParameters(namespace string) map[string]*Matrix
+	SetParameters(namespace string, parameters map[string]*Matrix) error
+}
+
+// Layer maps input vector to output vector
+type Layer interface {
+	Parameterized
+	Forward(g *Graph, x *Matrix) *Matrix
+}
+
+// SequenceLayer maps sequence of vectors to sequence of vectors
+type SequenceLayer interface {
+	Parameterized
+	ForwardSequence(g *Graph, x []*Matrix) []*Matrix
+}
+
+// State is recurrent state passed between time steps, cells use only fields they need
+type State struct {
+	H   *Matrix   // hidden state
+	C   *Matrix   // memory of LSTM like cells
+	Cin *Matrix   // inner memory of MultiplicativeNestedLSTM
+	Hs  []*Matrix // hidden states of every layer of IndRNN
+	X   *Matrix   // previous input, QRNN convolves two steps
+	T   int       // time step, IndRNN has step dependent weights
+}
+
+// RecurrentCell makes one time step of recurrent network
+type RecurrentCell interface {
+	Parameterized
+	// InitialState is zero state sequence starts with
+	InitialState() *State
+	// StepState consumes x and returns next state and output y
+	StepState(g *Graph, x *Matrix, s *State) (next *State, y *Matrix)
+}
+
+type rnnCell struct{ *RNN }
+
+func (c rnnCell) InitialState() *State { return &State{H: Mat(c.Whh.Rows, 1)} }
+func (c rnnCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, y := c.Step(g, x, s.H)
+	return &State{H: h, T: s.T + 1}, y
+}
+
+type outputlessRNNCell struct{ *OutputlessRNN }
+
+func (c outputlessRNNCell) InitialState() *State { return &State{H: Mat(c.Whh.Rows, 1)} }
+func (c outputlessRNNCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h := c.Step(g, x, s.H)
+	return &State{H: h, T: s.T + 1}, h
+}
+
+type deltaRNNCell struct{ *DeltaRNN }
+
+func (c deltaRNNCell) InitialState() *State { return &State{H: Mat(c.Wh.Rows, 1)} }
+func (c deltaRNNCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, y := c.Step(g, x, s.H)
+	return &State{H: h, T: s.T + 1}, y
+}
+
+type gruCell struct{ *GRU }
+
+func (c gruCell) InitialState() *State { return &State{H: Mat(c.Uz.Rows, 1)} }
+func (c gruCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, y := c.Step(g, x, s.H)
+	return &State{H: h, T: s.T + 1}, y
+}
+
+type layerNormGRUCell struct{ *LayerNormGRU }
+
+func (c layerNormGRUCell) InitialState() *State { return &State{H: Mat(c.Uz.Rows, 1)} }
+func (c layerNormGRUCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, y := c.Step(g, x, s.H)
+	return &State{H: h, T: s.T + 1}, y
+}
+
+type outputlessGRUCell struct{ *OutputlessGRU }
+
+func (c outputlessGRUCell) InitialState() *State { return &State{H: Mat(c.Uz.Rows, 1)} }
+func (c outputlessGRUCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h := c.Step(g, x, s.H)
+	return &State{H: h, T: s.T + 1}, h
+}
+
+// inputlessGRUCell ignores x
+type inputlessGRUCell struct{ *InputlessGRU }
+
+func (c inputlessGRUCell) InitialState() *State { return &State{H: Mat(c.Uz.Rows, 1)} }
+func (c inputlessGRUCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, y := c.Step(g, s.H)
+	return &State{H: h, T: s.T + 1}, y
+}
+
+type lstmCell struct{ *LSTM }
+
+func (c lstmCell) InitialState() *State {
+	return &State{H: Mat(c.Uf.Rows, 1), C: Mat(c.Uf.Rows, 1)}
+}
+func (c lstmCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, cell, y := c.Step(g, x, s.H, s.C)
+	return &State{H: h, C: cell, T: s.T + 1}, y
+}
+
+type layerNormLSTMCell struct{ *LayerNormLSTM }
+
+func (c layerNormLSTMCell) InitialState() *State {
+	return &State{H: Mat(c.Uf.Rows, 1), C: Mat(c.Uf.Rows, 1)}
+}
+func (c layerNormLSTMCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, cell, y := c.Step(g, x, s.H, s.C)
+	return &State{H: h, C: cell, T: s.T + 1}, y
+}
+
+type peepholeLSTMCell struct{ *PeepholeLSTM }
+
+func (c peepholeLSTMCell) InitialState() *State {
+	return &State{H: Mat(c.Uf.Rows, 1), C: Mat(c.Uf.Rows, 1)}
+}
+func (c peepholeLSTMCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, cell, y := c.Step(g, x, s.H, s.C)
+	return &State{H: h, C: cell, T: s.T + 1}, y
+}
+
+type outputlessLSTMCell struct{ *OutputlessLSTM }
+
+func (c outputlessLSTMCell) InitialState() *State {
+	return &State{H: Mat(c.Uf.Rows, 1), C: Mat(c.Uf.Rows, 1)}
+}
+func (c outputlessLSTMCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, cell := c.Step(g, x, s.H, s.C)
+	return &State{H: h, C: cell, T: s.T + 1}, h
+}
+
+type multiplicativeLSTMCell struct{ *MultiplicativeLSTM }
+
+func (c multiplicativeLSTMCell) InitialState() *State {
+	return &State{H: Mat(c.Uf.Rows, 1), C: Mat(c.Uf.Rows, 1)}
+}
+func (c multiplicativeLSTMCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, cell, y := c.Step(g, x, s.H, s.C)
+	return &State{H: h, C: cell, T: s.T + 1}, y
+}
+
+type multiplicativeNestedLSTMCell struct{ *MultiplicativeNestedLSTM }
+
+func (c multiplicativeNestedLSTMCell) InitialState() *State {
+	return &State{H: Mat(c.Uf.Rows, 1), C: Mat(c.Uf.Rows, 1), Cin: Mat(c.Uf.Rows, 1)}
+}
+func (c multiplicativeNestedLSTMCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, cell, cin, y := c.Step(g, x, s.H, s.C, s.Cin)
+	return &State{H: h, C: cell, Cin: cin, T: s.T + 1}, y
+}
+
+type mguCell struct{ *MGU }
+
+func (c mguCell) InitialState() *State { return &State{H: Mat(c.Uf.Rows, 1)} }
+func (c mguCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, y := c.Step(g, x, s.H)
+	return &State{H: h, T: s.T + 1}, y
+}
+
+type sruCell struct{ *SRU }
+
+func (c sruCell) InitialState() *State {
+	return &State{H: Mat(c.W.Rows, 1), C: Mat(c.W.Rows, 1)}
+}
+func (c sruCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, cell, y := c.Step(g, x, s.C)
+	return &State{H: h, C: cell, T: s.T + 1}, y
+}
+
+type qrnnCell struct{ *QRNN }
+
+func (c qrnnCell) InitialState() *State {
+	return &State{H: Mat(c.Wz.Rows, 1), C: Mat(c.Wz.Rows, 1), X: Mat(c.Wz.Columns, 1)}
+}
+func (c qrnnCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	h, cell, y := c.Step(g, x, s.X, s.C)
+	return &State{H: h, C: cell, X: x, T: s.T + 1}, y
+}
+
+// indRNNCell can run at most as many steps as IndRNN was made for
+type indRNNCell struct{ *IndRNN }
+
+func (c indRNNCell) InitialState() *State {
+	s := &State{Hs: make([]*Matrix, len(c.W))}
+	for l := range s.Hs {
+		s.Hs[l] = Mat(c.W[l].Rows, 1)
+	}
+	s.H = s.Hs[len(s.Hs)-1]
+	return s
+}
+func (c indRNNCell) StepState(g *Graph, x *Matrix, s *State) (*State, *Matrix) {
+	if s.T >= len(c.U[0]) {
+		panic(fmt.Errorf("IndRNN made for %d steps can not make step %d", len(c.U[0]), s.T))
+	}
+	hs, y := c.Step(g, s.T, x, s.Hs)
+	return &State{H: hs[len(hs)-1], Hs: hs, T: s.T + 1}, y
+}
+
+// AsRecurrentCell adapts any recurrent cell of the package to RecurrentCell interface
+func AsRecurrentCell(cell interface{}) (RecurrentCell, error) {
+	switch c := cell.(type) {
+	case RecurrentCell:
+		return c, nil
+	case *RNN:
+		return rnnCell{c}, nil
+	case *OutputlessRNN:
+		return outputlessRNNCell{c}, nil
+	case *DeltaRNN:
+		return deltaRNNCell{c}, nil
+	case *GRU:
+		return gruCell{c}, nil
+	case *LayerNormGRU:
+		return layerNormGRUCell{c}, nil
+	case *OutputlessGRU:
+		return outputlessGRUCell{c}, nil
+	case *InputlessGRU:
+		return inputlessGRUCell{c}, nil
+	case *LSTM:
+		return lstmCell{c}, nil
+	case *LayerNormLSTM:
+		return layerNormLSTMCell{c}, nil
+	case *PeepholeLSTM:
+		return peepholeLSTMCell{c}, nil
+	case *OutputlessLSTM:
+		return outputlessLSTMCell{c}, nil
+	case *MultiplicativeLSTM:
+		return multiplicativeLSTMCell{c}, nil
+	case *MultiplicativeNestedLSTM:
+		return multiplicativeNestedLSTMCell{c}, nil
+	case *IndRNN:
+		return indRNNCell{c}, nil
+	case *MGU:
+		return mguCell{c}, nil
+	case *SRU:
+		return sruCell{c}, nil
+	case *QRNN:
+		return qrnnCell{c}, nil
+	}
+	return nil, fmt.Errorf("%T is not recurrent cell", cell)
+}
+
+// vaeLayer outputs sample of latent distribution, use VAE directly to get KLD
+type vaeLayer struct{ *VAE }
+
+func (l vaeLayer) Forward(g *Graph, x *Matrix) *Matrix {
+	sample, _, _ := l.Step(g, x)
+	return sample
+}
+
+type temporalConvolutionLayer struct{ *TemporalConvolution }
+
+func (l temporalConvolutionLayer) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	return l.Step(g, x)
+}
+
+type dilatedTemporalConvolutionLayer struct{ *DilatedTemporalConvolution }
+
+func (l dilatedTemporalConvolutionLayer) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	l.SetInput(x)
+	y := make([]*Matrix, len(x))
+	for t := range y {
+		y[t] = l.FullStep(g, t)
+	}
+	return y
+}
+
+// AsLayer adapts vector to vector models of the package to Layer interface
+func AsLayer(layer interface{}) (Layer, error) {
+	switch l := layer.(type) {
+	case Layer:
+		return l, nil
+	case *VAE:
+		return vaeLayer{l}, nil
+	}
+	return nil, fmt.Errorf("%T is not layer", layer)
+}
+
+// cellLayer runs cell over sequence from initial state
+type cellLayer struct{ RecurrentCell }
+
+func (l cellLayer) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	return Unroll(g, l.RecurrentCell, nil, x, nil).Outputs
+}
+
+func (l cellLayer) ForwardMasked(g *Graph, x []*Matrix, mask []bool) []*Matrix {
+	return Unroll(g, l.RecurrentCell, nil, x, &UnrollOp{Mask: mask}).Outputs
+}
+
+// perStepLayer applies layer to every sequence element
+type perStepLayer struct{ Layer }
+
+func (l perStepLayer) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	return l.ForwardMasked(g, x, nil)
+}
+
+// ForwardMasked applies layer to valid steps only, padding steps get nil output
+func (l perStepLayer) ForwardMasked(g *Graph, x []*Matrix, mask []bool) []*Matrix {
+	y := make([]*Matrix, len(x))
+	for t := range x {
+		if mask == nil || mask[t] {
+			y[t] = l.Forward(g, x[t])
+		}
+	}
+	return y
+}
+
+// AsSequenceLayer adapts any layer, cell or convolution of the package to SequenceLayer interface,
+// cells run from their initial state and layers are applied to every step,
+// SRU and QRNN are sequence layers themselves projecting whole sequence at once
+func AsSequenceLayer(layer interface{}) (SequenceLayer, error) {
+	switch l := layer.(type) {
+	case SequenceLayer:
+		return l, nil
+	case *TemporalConvolution:
+		return temporalConvolutionLayer{l}, nil
+	case *DilatedTemporalConvolution:
+		return dilatedTemporalConvolutionLayer{l}, nil
+	}
+	if cell, e := AsRecurrentCell(layer); e == nil {
+		return cellLayer{cell}, nil
+	}
+	if l, e := AsLayer(layer); e == nil {
+		return perStepLayer{l}, nil
+	}
+	return nil, fmt.Errorf("%T is neither layer nor recurrent cell", layer)
+}
+
+// Sequential runs layers one after another, parameters of every layer are kept
+// under namespace_name where name is given to layer in Add
+type Sequential struct {
+	Names  []string
+	Layers []SequenceLayer
+}
+
+func MakeSequential() *Sequential {
+	return new(Sequential)
+}
+
+// Add appends layer adapted by AsSequenceLayer, names must be unique
+func (s *Sequential) Add(name string, layer interface{}) *Sequential {
+	if e := s.add(name, layer); e != nil {
+		panic(e)
+	}
+	return s
+}
+
+func (s *Sequential) add(name string, layer interface{}) error {
+	for _, n := range s.Names {
+		if n == name {
+			return fmt.Errorf("sequential already has layer %s", name)
+		}
+	}
+	l, e := AsSequenceLayer(layer)
+	if e != nil {
+		return fmt.Errorf("layer %s: %v", name, e)
+	}
+	s.Names = append(s.Names, name)
+	s.Layers = append(s.Layers, l)
+	return nil
+}
+
+func (s *Sequential) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	for _, l := range s.Layers {
+		x = l.ForwardSequence(g, x)
+	}
+	return x
+}
+
+func (s *Sequential) GetParameters(namespace string) map[string]*Matrix {
+	p := make(map[string]*Matrix)
+	for i, l := range s.Layers {
+		for k, v := range l.GetParameters(namespace + "_" + s.Names[i]) {
+			p[k] = v
+		}
+	}
+	return p
+}
+
+func (s *Sequential) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(s.GetParameters(namespace), parameters)
+}
+
+// LayerConfig is layer of SequentialConfig, Architecture is any registered architecture name
+type LayerConfig struct {
+	Name         string
+	Architecture string
+	Config       json.RawMessage
+}
+
+// SequentialConfig makes Sequential from registered architectures, so switching
+// e.g. from LSTM to GRU is one word change in config
+type SequentialConfig struct {
+	Namespace string // parameters namespace, Sequential if empty
+	Layers    []LayerConfig
+}
+
+// buildLayer makes model of registered architecture, its own parameters namespace is ignored
+func buildLayer(lc LayerConfig) (interface{}, error) {
+	b := &Bundle{Architecture: lc.Architecture, Config: lc.Config}
+	if e := b.build(); e != nil {
+		return nil, fmt.Errorf("layer %s: %v", lc.Name, e)
+	}
+	return b.Model, nil
+}
+
+func buildCell(lc LayerConfig) (RecurrentCell, error) {
+	model, e := buildLayer(lc)
+	if e != nil {
+		return nil, e
+	}
+	cell, e := AsRecurrentCell(model)
+	if e != nil {
+		return nil, fmt.Errorf("layer %s: %v", lc.Name, e)
+	}
+	return cell, nil
+}
+
+func init() {
+	RegisterArchitecture("Sequential", func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(SequentialConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("Sequential config: %v", e)
+		}
+		s := MakeSequential()
+		for _, lc := range c.Layers {
+			model, e := buildLayer(lc)
+			if e != nil {
+				return nil, nil, fmt.Errorf("Sequential %v", e)
+			}
+			if e := s.add(lc.Name, model); e != nil {
+				return nil, nil, fmt.Errorf("Sequential %v", e)
+			}
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = "Sequential"
+		}
+		return s, s.GetParameters(c.Namespace), nil
+	})
+}
@@ -0,0 +1,121 @@
+package gortex
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRecurrentCellAdapters(t *testing.T) {
+	cells := []interface{}{
+		MakeRNN(5, 7, 3), MakeOutputlessRNN(5, 7), MakeDeltaRNN(5, 7, 3),
+		MakeGRU(5, 7, 3), MakeOutputlessGRU(5, 7), MakeInputlessGRU(7, 3),
+		MakeLSTM(5, 7, 3), MakeOutputlessLSTM(5, 7), MakeMultiplicativeLSTM(5, 7, 3),
+		MakeMultiplicativeNestedLSTM(5, 7, 3), MakeIndRNN(2, 5, 4, 7, 3),
+	}
+	for _, c := range cells {
+		cell, e := AsRecurrentCell(c)
+		if e != nil {
+			t.Fatal(e)
+		}
+		G := &Graph{NeedsBackprop: true}
+		s := cell.InitialState()
+		var y *Matrix
+		for i := 0; i < 4; i++ {
+			s, y = cell.StepState(G, RandMat(5, 1), s)
+		}
+		if s.T != 4 || s.H.Rows != 7 || y == nil {
+			t.Fatalf("%T made wrong state %+v", c, s)
+		}
+		G.Backward()
+	}
+	// adapter steps exactly like cell
+	lstm := MakeLSTM(5, 7, 3)
+	cell, _ := AsRecurrentCell(lstm)
+	x := RandMat(5, 1)
+	G := &Graph{}
+	_, _, expected := lstm.Step(G, x, Mat(7, 1), Mat(7, 1))
+	_, actual := cell.StepState(G, x, cell.InitialState())
+	for i := range expected.W {
+		if expected.W[i] != actual.W[i] {
+			t.Fatal("adapter output differs from cell output")
+		}
+	}
+	if _, e := AsRecurrentCell(MakeVae(5, 3)); e == nil {
+		t.Fatal("VAE is not recurrent cell")
+	}
+}
+
+func TestSequential(t *testing.T) {
+	s := MakeSequential().
+		Add("conv", MakeTemporalConvolution(6, 5, 3, 1)).
+		Add("encoder", MakeLSTM(6, 8, 4)).
+		Add("vae", MakeVae(4, 2))
+	x := make([]*Matrix, 7)
+	for i := range x {
+		x[i] = RandMat(5, 1)
+	}
+	G := &Graph{NeedsBackprop: true}
+	y := s.ForwardSequence(G, x)
+	if len(y) != 5 || y[0].Rows != 2 {
+		t.Fatalf("wrong output %d x %d", len(y), y[0].Rows)
+	}
+	G.Backward()
+
+	p := s.GetParameters("model")
+	if p["model_encoder_Wf"] == nil || p["model_vae_W"] == nil || p["model_conv_bias"] == nil {
+		t.Fatal("layer parameters must be namespaced by layer names")
+	}
+	if e := s.SetParameters("model", p); e != nil {
+		t.Fatal(e)
+	}
+	delete(p, "model_encoder_Wf")
+	if e := s.SetParameters("model", p); e == nil {
+		t.Fatal("missing parameter must be reported")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate layer name must panic")
+		}
+	}()
+	s.Add("vae", MakeVae(2, 2))
+}
+
+func TestSequentialBundle(t *testing.T) {
+	config := func(cell string) json.RawMessage {
+		return json.RawMessage(`{"Layers": [
+			{"Name": "encoder", "Architecture": "` + cell + `", "Config": {"Input": 4, "Hidden": 6, "Output": 5}},
+			{"Name": "decoder", "Architecture": "OutputlessGRU", "Config": {"Input": 5, "Hidden": 3}}]}`)
+	}
+	for _, cell := range []string{"LSTM", "GRU", "MultiplicativeLSTM"} {
+		b, e := NewBundle("Sequential", config(cell))
+		if e != nil {
+			t.Fatal(e)
+		}
+		if b.Parameters["Sequential_decoder_Wz"] == nil {
+			t.Fatalf("%s sequential has parameters %v", cell, b.Parameters)
+		}
+		for name := range b.Parameters {
+			if !strings.HasPrefix(name, "Sequential_encoder_") && !strings.HasPrefix(name, "Sequential_decoder_") {
+				t.Fatalf("parameter %s is out of sequential namespace", name)
+			}
+		}
+		var buffer bytes.Buffer
+		if e = WriteBundle(&buffer, b); e != nil {
+			t.Fatal(e)
+		}
+		loaded, e := ReadBundle(&buffer)
+		if e != nil {
+			t.Fatal(e)
+		}
+		y := loaded.Model.(*Sequential).ForwardSequence(&Graph{}, []*Matrix{RandMat(4, 1), RandMat(4, 1)})
+		if len(y) != 2 || y[1].Rows != 3 {
+			t.Fatal("wrong output of loaded sequential")
+		}
+	}
+	if _, e := NewBundle("Sequential", json.RawMessage(`{"Layers": [{"Name": "x", "Architecture": "Nope"}]}`)); e == nil {
+		t.Fatal("unknown layer architecture must fail")
+	}
+}
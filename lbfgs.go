@@ -0,0 +1,371 @@
+package gortex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// L-BFGS optimizer for full batch problems with strong Wolfe line search,
+// follows Nocedal & Wright "Numerical Optimization" algorithms 7.4, 3.5 and 3.6
+
+type LBFGSOp struct {
+	LearningRate    float32 // initial step length, 1 is the natural choice for quasi-Newton steps
+	MaxIterations   int     // maximal number of iterations per Step
+	MaxEvaluations  int     // maximal number of closure evaluations per Step
+	ToleranceGrad   float32 // stop when max abs gradient is below
+	ToleranceChange float32 // stop when step or loss change is below
+	HistorySize     int     // number of curvature pairs to remember
+	LineSearch      bool    // use strong Wolfe line search, fixed step otherwise
+}
+
+type LBFGSRet struct {
+	Loss        float32
+	Iterations  int
+	Evaluations int
+}
+
+type LBFGS struct {
+	LBFGSOp
+	names      []string // fixed parameter order for flat vectors
+	d          []float64
+	t          float64
+	oldDirs    [][]float64
+	oldSteps   [][]float64
+	ro         []float64
+	hDiag      float64
+	prevGrad   []float64
+	Iteration  int
+	evaluation int
+}
+
+func NewLBFGS(ops LBFGSOp) *LBFGS {
+	o := new(LBFGS)
+	o.LBFGSOp = ops
+	if o.LearningRate == 0 {
+		o.LearningRate = 1
+	}
+	if o.MaxIterations == 0 {
+		o.MaxIterations = 20
+	}
+	if o.MaxEvaluations == 0 {
+		o.MaxEvaluations = o.MaxIterations * 5 / 4
+	}
+	if o.ToleranceGrad == 0 {
+		o.ToleranceGrad = 1e-7
+	}
+	if o.ToleranceChange == 0 {
+		o.ToleranceChange = 1e-9
+	}
+	if o.HistorySize == 0 {
+		o.HistorySize = 100
+	}
+	return o
+}
+
+func (o *LBFGS) flatten(model map[string]*Matrix, grad bool) []float64 {
+	if o.names == nil {
+		for name := range model {
+			o.names = append(o.names, name)
+		}
+		sort.Strings(o.names)
+	}
+	var flat []float64
+	for _, name := range o.names {
+		m, ok := model[name]
+		if !ok {
+			panic(fmt.Errorf("parameter %s disappeared from the model", name))
+		}
+		src := m.W
+		if grad {
+			src = m.DW
+		}
+		for _, v := range src {
+			flat = append(flat, float64(v))
+		}
+	}
+	return flat
+}
+
+// addStep moves weights by t*d
+func (o *LBFGS) addStep(model map[string]*Matrix, t float64, d []float64) {
+	offset := 0
+	for _, name := range o.names {
+		m := model[name]
+		for i := range m.W {
+			m.W[i] += float32(t * d[offset+i])
+		}
+		offset += m.Numel()
+	}
+}
+
+func (o *LBFGS) setWeights(model map[string]*Matrix, x []float64) {
+	offset := 0
+	for _, name := range o.names {
+		m := model[name]
+		for i := range m.W {
+			m.W[i] = float32(x[offset+i])
+		}
+		offset += m.Numel()
+	}
+}
+
+func (o *LBFGS) evaluate(model map[string]*Matrix, closure func() float32) (float64, []float64) {
+	ResetGradients(model)
+	loss := closure()
+	o.evaluation++
+	return float64(loss), o.flatten(model, true)
+}
+
+// directional evaluates loss and gradient at x + t*d and leaves weights at x
+func (o *LBFGS) directional(model map[string]*Matrix, closure func() float32, x []float64, t float64, d []float64) (float64, []float64) {
+	o.addStep(model, t, d)
+	loss, grad := o.evaluate(model, closure)
+	o.setWeights(model, x)
+	return loss, grad
+}
+
+func dot64(x, y []float64) (s float64) {
+	for i := range x {
+		s += x[i] * y[i]
+	}
+	return
+}
+
+func maxAbs64(x []float64) (m float64) {
+	for _, v := range x {
+		m = math.Max(m, math.Abs(v))
+	}
+	return
+}
+
+func clone64(x []float64) []float64 {
+	return append([]float64(nil), x...)
+}
+
+// Step runs L-BFGS iterations, closure must build graph, run Backward and return loss,
+// gradients are reset before every closure call
+func (o *LBFGS) Step(model map[string]*Matrix, closure func() float32) LBFGSRet {
+	o.evaluation = 0
+	loss, g := o.evaluate(model, closure)
+	ret := LBFGSRet{Loss: float32(loss)}
+	if maxAbs64(g) <= float64(o.ToleranceGrad) {
+		ret.Evaluations = o.evaluation
+		return ret
+	}
+	tolChange := float64(o.ToleranceChange)
+	for n := 0; n < o.MaxIterations; n++ {
+		o.Iteration++
+		ret.Iterations++
+		if o.Iteration == 1 {
+			o.d = make([]float64, len(g))
+			for i := range g {
+				o.d[i] = -g[i]
+			}
+			o.hDiag = 1
+		} else {
+			// update curvature pairs
+			y := make([]float64, len(g))
+			s := make([]float64, len(g))
+			for i := range g {
+				y[i] = g[i] - o.prevGrad[i]
+				s[i] = o.d[i] * o.t
+			}
+			ys := dot64(y, s)
+			if ys > 1e-10 {
+				if len(o.oldDirs) == o.HistorySize {
+					o.oldDirs, o.oldSteps, o.ro = o.oldDirs[1:], o.oldSteps[1:], o.ro[1:]
+				}
+				o.oldDirs = append(o.oldDirs, y)
+				o.oldSteps = append(o.oldSteps, s)
+				o.ro = append(o.ro, 1/ys)
+				o.hDiag = ys / dot64(y, y)
+			}
+			// two loop recursion for approximate inverse hessian times gradient
+			k := len(o.oldDirs)
+			al := make([]float64, k)
+			q := make([]float64, len(g))
+			for i := range g {
+				q[i] = -g[i]
+			}
+			for i := k - 1; i >= 0; i-- {
+				al[i] = dot64(o.oldSteps[i], q) * o.ro[i]
+				for j := range q {
+					q[j] -= al[i] * o.oldDirs[i][j]
+				}
+			}
+			for j := range q {
+				q[j] *= o.hDiag
+			}
+			for i := 0; i < k; i++ {
+				be := dot64(o.oldDirs[i], q) * o.ro[i]
+				for j := range q {
+					q[j] += o.oldSteps[i][j] * (al[i] - be)
+				}
+			}
+			o.d = q
+		}
+		o.prevGrad = clone64(g)
+		prevLoss := loss
+
+		// initial step length
+		if o.Iteration == 1 {
+			var l1 float64
+			for _, v := range g {
+				l1 += math.Abs(v)
+			}
+			o.t = math.Min(1, 1/l1) * float64(o.LearningRate)
+		} else {
+			o.t = float64(o.LearningRate)
+		}
+
+		gtd := dot64(g, o.d)
+		if gtd > -tolChange {
+			// not a descent direction
+			break
+		}
+
+		if o.LineSearch {
+			x := o.flatten(model, false)
+			loss, g, o.t = o.strongWolfe(model, closure, x, o.t, o.d, loss, g, gtd)
+			o.setWeights(model, x)
+			o.addStep(model, o.t, o.d)
+		} else {
+			o.addStep(model, o.t, o.d)
+			if n != o.MaxIterations-1 {
+				loss, g = o.evaluate(model, closure)
+			}
+		}
+		ret.Loss = float32(loss)
+
+		if o.evaluation >= o.MaxEvaluations {
+			break
+		}
+		if maxAbs64(g) <= float64(o.ToleranceGrad) {
+			break
+		}
+		if maxAbs64(o.d)*math.Abs(o.t) <= tolChange {
+			break
+		}
+		if math.Abs(loss-prevLoss) < tolChange {
+			break
+		}
+	}
+	ret.Evaluations = o.evaluation
+	return ret
+}
+
+// cubicInterpolate minimizes cubic through (x1,f1,g1) and (x2,f2,g2) within bounds
+func cubicInterpolate(x1, f1, g1, x2, f2, g2, xmin, xmax float64) float64 {
+	d1 := g1 + g2 - 3*(f1-f2)/(x1-x2)
+	d2square := d1*d1 - g1*g2
+	if d2square >= 0 {
+		d2 := math.Sqrt(d2square)
+		var pos float64
+		if x1 <= x2 {
+			pos = x2 - (x2-x1)*((g2+d2-d1)/(g2-g1+2*d2))
+		} else {
+			pos = x1 - (x1-x2)*((g1+d2-d1)/(g1-g2+2*d2))
+		}
+		return math.Min(math.Max(pos, xmin), xmax)
+	}
+	return (xmin + xmax) / 2
+}
+
+func interpolateBracket(x1, f1, g1, x2, f2, g2 float64) float64 {
+	return cubicInterpolate(x1, f1, g1, x2, f2, g2, math.Min(x1, x2), math.Max(x1, x2))
+}
+
+func (o *LBFGS) strongWolfe(model map[string]*Matrix, closure func() float32, x []float64, t float64, d []float64, f float64, g []float64, gtd float64) (float64, []float64, float64) {
+	const c1, c2, maxLS = 1e-4, 0.9, 25
+	tolChange := float64(o.ToleranceChange)
+	dNorm := maxAbs64(d)
+
+	fNew, gNew := o.directional(model, closure, x, t, d)
+	gtdNew := dot64(gNew, d)
+
+	tPrev, fPrev, gPrev, gtdPrev := 0.0, f, g, gtd
+	done := false
+	iteration := 0
+	var bracket, bracketF, bracketGtd [2]float64
+	var bracketG [2][]float64
+	single := false
+	for ; iteration < maxLS; iteration++ {
+		if fNew > f+c1*t*gtd || (iteration > 1 && fNew >= fPrev) {
+			bracket, bracketF, bracketG, bracketGtd = [2]float64{tPrev, t}, [2]float64{fPrev, fNew}, [2][]float64{gPrev, gNew}, [2]float64{gtdPrev, gtdNew}
+			break
+		}
+		if math.Abs(gtdNew) <= -c2*gtd {
+			single, done = true, true
+			break
+		}
+		if gtdNew >= 0 {
+			bracket, bracketF, bracketG, bracketGtd = [2]float64{tPrev, t}, [2]float64{fPrev, fNew}, [2][]float64{gPrev, gNew}, [2]float64{gtdPrev, gtdNew}
+			break
+		}
+		// extrapolate
+		minStep := t + 0.01*(t-tPrev)
+		maxStep := t * 10
+		tmp := t
+		t = cubicInterpolate(tPrev, fPrev, gtdPrev, t, fNew, gtdNew, minStep, maxStep)
+		tPrev, fPrev, gPrev, gtdPrev = tmp, fNew, gNew, gtdNew
+		fNew, gNew = o.directional(model, closure, x, t, d)
+		gtdNew = dot64(gNew, d)
+	}
+	if single {
+		return fNew, gNew, t
+	}
+	if iteration == maxLS {
+		bracket, bracketF, bracketG, bracketGtd = [2]float64{0, t}, [2]float64{f, fNew}, [2][]float64{g, gNew}, [2]float64{gtd, gtdNew}
+	}
+
+	// zoom into bracket
+	insufficientProgress := false
+	low, high := 0, 1
+	if bracketF[0] > bracketF[1] {
+		low, high = 1, 0
+	}
+	for !done && iteration < maxLS {
+		if math.Abs(bracket[1]-bracket[0])*dNorm < tolChange {
+			break
+		}
+		t = interpolateBracket(bracket[0], bracketF[0], bracketGtd[0], bracket[1], bracketF[1], bracketGtd[1])
+		bmax, bmin := math.Max(bracket[0], bracket[1]), math.Min(bracket[0], bracket[1])
+		eps := 0.1 * (bmax - bmin)
+		if math.Min(bmax-t, t-bmin) < eps {
+			// interpolation is too close to boundary
+			if insufficientProgress || t >= bmax || t <= bmin {
+				if math.Abs(t-bmax) < math.Abs(t-bmin) {
+					t = bmax - eps
+				} else {
+					t = bmin + eps
+				}
+				insufficientProgress = false
+			} else {
+				insufficientProgress = true
+			}
+		} else {
+			insufficientProgress = false
+		}
+		fNew, gNew = o.directional(model, closure, x, t, d)
+		gtdNew = dot64(gNew, d)
+		iteration++
+
+		if fNew > f+c1*t*gtd || fNew >= bracketF[low] {
+			bracket[high], bracketF[high], bracketG[high], bracketGtd[high] = t, fNew, gNew, gtdNew
+			if bracketF[0] <= bracketF[1] {
+				low, high = 0, 1
+			} else {
+				low, high = 1, 0
+			}
+		} else {
+			if math.Abs(gtdNew) <= -c2*gtd {
+				done = true
+			} else if gtdNew*(bracket[high]-bracket[low]) >= 0 {
+				bracket[high], bracketF[high], bracketG[high], bracketGtd[high] = bracket[low], bracketF[low], bracketG[low], bracketGtd[low]
+			}
+			bracket[low], bracketF[low], bracketG[low], bracketGtd[low] = t, fNew, gNew, gtdNew
+		}
+	}
+	return bracketF[low], bracketG[low], bracket[low]
+}
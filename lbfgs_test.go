@@ -0,0 +1,50 @@
+package gortex
+
+import "testing"
+
+func TestLBFGSFitLine(t *testing.T) {
+	X := []float32{1, 0}
+	Y := []float32{1, 2}
+	for _, lineSearch := range []bool{true, false} {
+		A := RandMat(1, 1)
+		B := RandMat(1, 1)
+		model := map[string]*Matrix{"A": A, "B": B}
+		optimizer := NewLBFGS(LBFGSOp{LineSearch: lineSearch, MaxIterations: 100})
+		ret := optimizer.Step(model, func() float32 {
+			G := &Graph{NeedsBackprop: true}
+			var cost float32
+			for i := range X {
+				x := Mat(1, 1)
+				x.Set(0, 0, X[i])
+				y := Mat(1, 1)
+				y.Set(0, 0, Y[i])
+				cost += G.MSE(G.Add(G.Mul(A, x), B), y)
+			}
+			G.Backward()
+			return cost
+		})
+		t.Logf("line search: %v loss: %f iterations: %d evaluations: %d", lineSearch, ret.Loss, ret.Iterations, ret.Evaluations)
+		if Abs(A.W[0]+1) > 1e-3 || Abs(B.W[0]-2) > 1e-3 {
+			t.Fatalf("A = %f, B = %f but must be -1 and 2", A.W[0], B.W[0])
+		}
+	}
+}
+
+func TestLBFGSRosenbrock(t *testing.T) {
+	P := Mat(2, 1)
+	P.W[0], P.W[1] = -1.5, 2
+	model := map[string]*Matrix{"P": P}
+	optimizer := NewLBFGS(LBFGSOp{LineSearch: true, MaxIterations: 200, MaxEvaluations: 1000})
+	ret := optimizer.Step(model, func() float32 {
+		// (1-x)^2 + 100(y-x^2)^2
+		x, y := P.W[0], P.W[1]
+		loss := (1-x)*(1-x) + 100*(y-x*x)*(y-x*x)
+		P.DW[0] = -2*(1-x) - 400*x*(y-x*x)
+		P.DW[1] = 200 * (y - x*x)
+		return loss
+	})
+	t.Logf("loss: %f iterations: %d evaluations: %d", ret.Loss, ret.Iterations, ret.Evaluations)
+	if Abs(P.W[0]-1) > 1e-2 || Abs(P.W[1]-1) > 1e-2 {
+		t.Fatalf("minimum must be at (1, 1) but %v", P.W)
+	}
+}
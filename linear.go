@@ -0,0 +1,173 @@
+package gortex
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Activation is element wise nonlinearity
+type Activation func(g *Graph, m *Matrix) *Matrix
+
+var activations = map[string]Activation{
+	"":            func(g *Graph, m *Matrix) *Matrix { return m },
+	"Linear":      func(g *Graph, m *Matrix) *Matrix { return m },
+	"Tanh":        func(g *Graph, m *Matrix) *Matrix { return g.Tanh(m) },
+	"Sigmoid":     func(g *Graph, m *Matrix) *Matrix { return g.Sigmoid(m) },
+	"Relu":        func(g *Graph, m *Matrix) *Matrix { return g.Relu(m) },
+	"Selu":        func(g *Graph, m *Matrix) *Matrix { return g.Selu(m) },
+	"BipolarRelu": func(g *Graph, m *Matrix) *Matrix { return g.BipolarRelu(m) },
+	"BipolarElu":  func(g *Graph, m *Matrix) *Matrix { return g.BipolarElu(m) },
+	"BipolarSelu": func(g *Graph, m *Matrix) *Matrix { return g.BipolarSelu(m) },
+}
+
+// ActivationByName returns activation named as Graph method, empty name and Linear mean identity
+func ActivationByName(name string) (Activation, error) {
+	if a, ok := activations[name]; ok {
+		return a, nil
+	}
+	return nil, fmt.Errorf("unknown activation %s", name)
+}
+
+// Linear is dense layer y = W*x + B
+type Linear struct {
+	W *Matrix
+	B *Matrix // nil if layer has no bias
+}
+
+// MakeLinear makes XavierNormal weights and zero bias by default
+func MakeLinear(x_size, out_size int, bias bool, options ...InitOption) *Linear {
+	in := NewInitialization(XavierNormal{}, XavierNormal{}, Constant{}, options...)
+	l := &Linear{W: in.weights(out_size, x_size)}
+	if bias {
+		l.B = in.biases(out_size)
+	}
+	return l
+}
+
+func (l *Linear) GetParameters(namespace string) map[string]*Matrix {
+	p := map[string]*Matrix{namespace + "_W": l.W}
+	if l.B != nil {
+		p[namespace+"_B"] = l.B
+	}
+	return p
+}
+
+func (l *Linear) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(l.GetParameters(namespace), parameters)
+}
+
+func (l *Linear) Forward(g *Graph, x *Matrix) *Matrix {
+	y := g.Mul(l.W, x)
+	if l.B != nil {
+		y = g.Add(y, l.B)
+	}
+	return y
+}
+
+// MLP is multilayer perceptron, activation is not applied to last layer output
+type MLP struct {
+	Layers     []*Linear
+	Activation string
+	Dropout    float32 // dropout probability of hidden layers outputs, applied during training only
+	Residual   bool    // add layer input to its output when their sizes match, last layer excluded
+	activation Activation
+}
+
+// MakeMLP makes MLP of len(sizes)-1 biased linear layers, sizes[0] is input size
+func MakeMLP(sizes []int, activation string, dropout float32, residual bool, options ...InitOption) *MLP {
+	a, e := ActivationByName(activation)
+	if e != nil {
+		panic(e)
+	}
+	if len(sizes) < 2 {
+		panic(fmt.Errorf("MLP needs input and output sizes but %v given", sizes))
+	}
+	mlp := &MLP{Activation: activation, Dropout: dropout, Residual: residual, activation: a}
+	for i := 1; i < len(sizes); i++ {
+		mlp.Layers = append(mlp.Layers, MakeLinear(sizes[i-1], sizes[i], true, options...))
+	}
+	return mlp
+}
+
+func (mlp *MLP) GetParameters(namespace string) map[string]*Matrix {
+	p := make(map[string]*Matrix)
+	for i, l := range mlp.Layers {
+		for k, v := range l.GetParameters(fmt.Sprintf("%s_layer%d", namespace, i)) {
+			p[k] = v
+		}
+	}
+	return p
+}
+
+func (mlp *MLP) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(mlp.GetParameters(namespace), parameters)
+}
+
+func (mlp *MLP) Forward(g *Graph, x *Matrix) *Matrix {
+	if mlp.activation == nil {
+		mlp.activation, _ = ActivationByName(mlp.Activation)
+	}
+	last := len(mlp.Layers) - 1
+	for i, l := range mlp.Layers {
+		y := l.Forward(g, x)
+		if i < last {
+			y = mlp.activation(g, y)
+			if mlp.Residual && y.Rows == x.Rows {
+				y = g.Add(y, x)
+			}
+			if mlp.Dropout > 0 {
+				y = g.Dropout(mlp.Dropout, y)
+			}
+		}
+		x = y
+	}
+	return x
+}
+
+// LinearConfig makes Linear under Linear architecture name
+type LinearConfig struct {
+	Namespace string // parameters namespace, Linear if empty
+	Input     int
+	Output    int
+	NoBias    bool
+}
+
+// MLPConfig makes MLP under MLP architecture name
+type MLPConfig struct {
+	Namespace  string // parameters namespace, MLP if empty
+	Sizes      []int
+	Activation string
+	Dropout    float32
+	Residual   bool
+}
+
+func init() {
+	RegisterArchitecture("Linear", func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(LinearConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("Linear config: %v", e)
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = "Linear"
+		}
+		l := MakeLinear(c.Input, c.Output, !c.NoBias)
+		return l, l.GetParameters(c.Namespace), nil
+	})
+	RegisterArchitecture("MLP", func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(MLPConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("MLP config: %v", e)
+		}
+		if _, e := ActivationByName(c.Activation); e != nil {
+			return nil, nil, fmt.Errorf("MLP config: %v", e)
+		}
+		if len(c.Sizes) < 2 {
+			return nil, nil, fmt.Errorf("MLP config: needs input and output sizes but %v given", c.Sizes)
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = "MLP"
+		}
+		mlp := MakeMLP(c.Sizes, c.Activation, c.Dropout, c.Residual)
+		return mlp, mlp.GetParameters(c.Namespace), nil
+	})
+}
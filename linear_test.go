@@ -0,0 +1,71 @@
+package gortex
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinear(t *testing.T) {
+	l := MakeLinear(4, 3, true, WithWeights(Normal{0, 1}))
+	copy(l.B.W, []float32{1, 2, 3})
+	x := RandMat(4, 1)
+	G := &Graph{NeedsBackprop: true}
+	y := l.Forward(G, x)
+	for i := 0; i < 3; i++ {
+		expected := l.B.W[i]
+		for j := 0; j < 4; j++ {
+			expected += l.W.W[4*i+j] * x.W[j]
+		}
+		if math.Abs(float64(expected-y.W[i])) > 1e-5 {
+			t.Fatalf("output %d is %f instead of %f", i, y.W[i], expected)
+		}
+	}
+	if p := MakeLinear(4, 3, false).GetParameters("l"); len(p) != 1 || p["l_W"] == nil {
+		t.Fatal("linear layer without bias must have weights only")
+	}
+}
+
+func TestMLP(t *testing.T) {
+	mlp := MakeMLP([]int{6, 8, 8, 2}, "Relu", 0.1, true)
+	s := MakeSequential().Add("encoder", MakeGRU(5, 7, 6)).Add("head", mlp)
+	x := []*Matrix{RandMat(5, 1), RandMat(5, 1), RandMat(5, 1)}
+	G := &Graph{NeedsBackprop: true}
+	y := s.ForwardSequence(G, x)
+	if len(y) != 3 || y[2].Rows != 2 {
+		t.Fatalf("wrong output %d x %d", len(y), y[2].Rows)
+	}
+	G.Backward()
+
+	// parameters survive save and load
+	p := s.GetParameters("model")
+	if p["model_head_layer2_W"] == nil || p["model_head_layer0_B"] == nil {
+		t.Fatal("MLP parameters must be namespaced by layer numbers")
+	}
+	name := filepath.Join(t.TempDir(), "model")
+	if e := SaveModel(name, p); e != nil {
+		t.Fatal(e)
+	}
+	loaded, e := LoadModel(name)
+	if e != nil {
+		t.Fatal(e)
+	}
+	restored := MakeSequential().Add("encoder", MakeGRU(5, 7, 6)).Add("head", MakeMLP([]int{6, 8, 8, 2}, "Relu", 0.1, true))
+	if e := restored.SetParameters("model", loaded); e != nil {
+		t.Fatal(e)
+	}
+	expected, actual := s.ForwardSequence(&Graph{}, x), restored.ForwardSequence(&Graph{}, x)
+	for i := range expected[2].W {
+		if expected[2].W[i] != actual[2].W[i] {
+			t.Fatal("restored MLP differs from saved one")
+		}
+	}
+
+	if _, e := NewBundle("MLP", &MLPConfig{Sizes: []int{4, 2}, Activation: "Swish"}); e == nil {
+		t.Fatal("unknown activation must be reported")
+	}
+	b, e := NewBundle("MLP", &MLPConfig{Sizes: []int{4, 5, 2}, Activation: "Tanh"})
+	if e != nil || len(b.Parameters) != 4 || b.Parameters["MLP_layer1_W"].Rows != 2 {
+		t.Fatalf("MLP is not registered properly: %v", e)
+	}
+}
@@ -0,0 +1,101 @@
+package gortex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/vseledkin/gortex/assembler"
+)
+
+type LSUVOp struct {
+	Tolerance     float32 // allowed difference of output variance from 1, 0.1 if zero
+	MaxIterations int     // scaling iterations per weight matrix, 10 if zero
+	Orthogonal    bool    // start from orthogonal weights as paper does
+}
+
+type LSUVRet struct {
+	Order      []string           // weights in order they were initialized
+	Variances  map[string]float32 // output variances of weights after initialization
+	Iterations int                // forward passes over samples made
+	Skipped    []string           // weights model never multiplied by or convolved with, left as is
+}
+
+// LSUV is data driven Layer-Sequential Unit Variance initialization Mishkin & Matas, 2015 https://arxiv.org/abs/1511.06422
+// of assembled model, every weight matrix multiplied by or used as convolution kernel in model is scaled
+// in order of use until variance of its products over samples is 1, model is anything AsSequenceLayer adapts,
+// samples are input sequences, biases, lookup tables and weights not used by Graph.Mul or Graph.Conv are left as is
+// and reported as skipped
+func LSUV(model interface{}, namespace string, samples [][]*Matrix, op *LSUVOp) (*LSUVRet, error) {
+	layer, e := AsSequenceLayer(model)
+	if e != nil {
+		return nil, fmt.Errorf("LSUV: %v", e)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("LSUV needs samples")
+	}
+	if op == nil {
+		op = new(LSUVOp)
+	}
+	tolerance, iterations := op.Tolerance, op.MaxIterations
+	if tolerance == 0 {
+		tolerance = 0.1
+	}
+	if iterations == 0 {
+		iterations = 10
+	}
+	names := make(map[*Matrix]string)
+	for name, m := range layer.GetParameters(namespace) {
+		if m.Rows > 1 && m.Columns > 1 && !m.IsLookupTable() {
+			if m.IsCompressed() {
+				return nil, fmt.Errorf("LSUV: %s is compressed, make model trainable first", name)
+			}
+			names[m] = name
+		}
+	}
+	ret := &LSUVRet{Variances: make(map[string]float32)}
+	// run over samples collecting products of weights of interest
+	run := func(observe func(m, out *Matrix)) {
+		ret.Iterations++
+		for _, sample := range samples {
+			g := &Graph{multiplied: observe}
+			layer.ForwardSequence(g, sample)
+		}
+	}
+	seen := make(map[*Matrix]bool)
+	var order []*Matrix
+	run(func(m, out *Matrix) {
+		if _, ok := names[m]; ok && !seen[m] {
+			seen[m] = true
+			order = append(order, m)
+		}
+	})
+	for m, name := range names {
+		if !seen[m] {
+			ret.Skipped = append(ret.Skipped, name)
+		}
+	}
+	sort.Strings(ret.Skipped)
+	for _, w := range order {
+		if op.Orthogonal {
+			Orthogonal{}.Initialize(w)
+		}
+		var variance float32
+		for i := 0; ; i++ {
+			var outputs []float32
+			run(func(m, out *Matrix) {
+				if m == w {
+					outputs = append(outputs, out.W...)
+				}
+			})
+			_, variance = Moments(&Matrix{Rows: len(outputs), Columns: 1, W: outputs})
+			if variance == 0 || Abs(variance-1) < tolerance || i == iterations {
+				break
+			}
+			assembler.Sscale(float32(1/math.Sqrt(float64(variance))), w.W)
+		}
+		ret.Order = append(ret.Order, names[w])
+		ret.Variances[names[w]] = variance
+	}
+	return ret, nil
+}
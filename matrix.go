@@ -4,15 +4,32 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
 
 	"github.com/vseledkin/gortex/assembler"
 )
 
 type Matrix struct {
-	Rows    int //number of rows
-	Columns int // number of columns
-	W       []float32
-	DW      []float32 `json:"-"`
+	Rows     int //number of rows
+	Columns  int // number of columns
+	W        []float32
+	DW       []float32    `json:"-"`
+	Half     []uint16     `json:"-"` // compressed weights replacing W, see Compress
+	HalfType DType        `json:"-"`
+	touched  map[int]bool // columns looked up since last optimizer step
+	lookup   int32        // 1 once matrix was used by Graph.Lookup for training, atomic
+	dense    int32        // 1 if matrix got dense gradient since last optimizer step, atomic
+}
+
+// touchLocks guard touched columns, matrix picks lock by its address
+// so lookups of different tables do not wait for each other
+var touchLocks [64]sync.Mutex
+
+func (m *Matrix) touchLock() *sync.Mutex {
+	return &touchLocks[(uintptr(unsafe.Pointer(m))>>4)%uintptr(len(touchLocks))]
 }
 
 func (m *Matrix) SameAs() (mm *Matrix) {
@@ -104,6 +121,77 @@ func (m *Matrix) Numel() int {
 	return len(m.W)
 }
 
+// Touch marks column as used by Graph.Lookup so optimizer can update it sparsely
+func (m *Matrix) Touch(col int) {
+	lock := m.touchLock()
+	lock.Lock()
+	if m.touched == nil {
+		m.touched = make(map[int]bool)
+	}
+	m.touched[col] = true
+	lock.Unlock()
+	if atomic.LoadInt32(&m.lookup) == 0 {
+		atomic.StoreInt32(&m.lookup, 1)
+	}
+}
+
+// TouchAll marks matrix as getting gradient of all columns, e.g. lookup table tied
+// with softmax weights, optimizer then updates it densely
+func (m *Matrix) TouchAll() {
+	if atomic.LoadInt32(&m.dense) == 0 {
+		atomic.StoreInt32(&m.dense, 1)
+	}
+}
+
+// IsTouchedAll reports if matrix got dense gradient since last ResetTouched
+func (m *Matrix) IsTouchedAll() bool {
+	return atomic.LoadInt32(&m.dense) == 1
+}
+
+// Touched returns sorted list of columns marked since last ResetTouched
+func (m *Matrix) Touched() []int {
+	lock := m.touchLock()
+	lock.Lock()
+	cols := make([]int, 0, len(m.touched))
+	for col := range m.touched {
+		cols = append(cols, col)
+	}
+	lock.Unlock()
+	sort.Ints(cols)
+	return cols
+}
+
+// MergeTouched marks columns touched in other, used when collecting gradients from clones
+func (m *Matrix) MergeTouched(other *Matrix) {
+	for _, col := range other.Touched() {
+		m.Touch(col)
+	}
+	if other.IsTouchedAll() {
+		m.TouchAll()
+	}
+}
+
+// ResetTouched forgets touched columns and dense use but keeps matrix known as lookup table
+func (m *Matrix) ResetTouched() {
+	lock := m.touchLock()
+	lock.Lock()
+	if len(m.touched) > 0 {
+		m.touched = make(map[int]bool)
+	}
+	lock.Unlock()
+	atomic.StoreInt32(&m.dense, 0)
+}
+
+// markLookupTable makes matrix known as lookup table without touching columns
+func (m *Matrix) markLookupTable() {
+	atomic.StoreInt32(&m.lookup, 1)
+}
+
+// IsLookupTable reports if matrix was ever used by Graph.Lookup for training
+func (m *Matrix) IsLookupTable() bool {
+	return atomic.LoadInt32(&m.lookup) == 1
+}
+
 func MatFromSlice(w [][]float32) *Matrix {
 	M := new(Matrix)
 	M.Rows = len(w)
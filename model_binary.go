@@ -0,0 +1,403 @@
+package gortex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+/*
+	Binary model format, all numbers are little endian
+
+	magic      [4]byte "GTXM"
+	version    uint32
+	count      uint32 number of tensors
+	index      count entries of
+		name_len uint16
+		name     [name_len]byte
+		dtype    uint8
+		rows     uint32
+		columns  uint32
+		offset   uint64 tensor data offset from payload start
+	padding    up to ModelAlignment boundary
+	payload    tensors data, every tensor starts at ModelAlignment boundary
+	checksum   uint32 crc32 (IEEE) of everything above
+*/
+
+const (
+	ModelMagic     = "GTXM"
+	ModelVersion   = 1
+	ModelAlignment = 64 // tensors are aligned for SIMD loads and memory mapping
+)
+
+type DType uint8
+
+const (
+	Float32  DType = iota
+	Float16        // IEEE 754 half precision
+	BFloat16       // float32 with mantissa cut to 7 bits
+)
+
+func (d DType) Size() int {
+	switch d {
+	case Float32:
+		return 4
+	case Float16, BFloat16:
+		return 2
+	}
+	panic(fmt.Errorf("unknown dtype %d", d))
+}
+
+func (d DType) String() string {
+	switch d {
+	case Float32:
+		return "float32"
+	case Float16:
+		return "float16"
+	case BFloat16:
+		return "bfloat16"
+	}
+	return fmt.Sprintf("dtype(%d)", uint8(d))
+}
+
+func (d DType) valid() bool {
+	return d == Float32 || d == Float16 || d == BFloat16
+}
+
+// storage is dtype matrix keeps its weights in
+func storage(m *Matrix) DType {
+	if m.Half != nil {
+		return m.HalfType
+	}
+	return Float32
+}
+
+// TensorInfo describes one tensor of binary model file
+type TensorInfo struct {
+	Name    string
+	DType   DType
+	Rows    int
+	Columns int
+	Offset  int64 // from payload start
+}
+
+func (ti *TensorInfo) Size() int64 {
+	return int64(ti.Rows*ti.Columns) * int64(ti.DType.Size())
+}
+
+func align(n int64) int64 {
+	return (n + ModelAlignment - 1) / ModelAlignment * ModelAlignment
+}
+
+// modelIndex lays out tensors of model sorted by name
+func modelIndex(m map[string]*Matrix, dtype func(m *Matrix) DType) (index []*TensorInfo, headerSize int64) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headerSize = 4 + 4 + 4
+	var offset int64
+	for _, name := range names {
+		ti := &TensorInfo{Name: name, DType: dtype(m[name]), Rows: m[name].Rows, Columns: m[name].Columns, Offset: offset}
+		index = append(index, ti)
+		headerSize += 2 + int64(len(name)) + 1 + 4 + 4 + 8
+		offset = align(offset + ti.Size())
+	}
+	return
+}
+
+// WriteModelBinary writes model in binary format, compressed matrices keep their half precision type
+func WriteModelBinary(w io.Writer, m map[string]*Matrix) error {
+	return writeModelBinary(w, m, storage)
+}
+
+// WriteModelBinaryAs writes every tensor of model as dtype, Float16 or BFloat16 halve file size
+func WriteModelBinaryAs(w io.Writer, m map[string]*Matrix, dtype DType) error {
+	if !dtype.valid() {
+		return fmt.Errorf("unknown dtype %d", dtype)
+	}
+	return writeModelBinary(w, m, func(*Matrix) DType { return dtype })
+}
+
+func writeModelBinary(w io.Writer, m map[string]*Matrix, dtype func(m *Matrix) DType) error {
+	for name, v := range m {
+		if len(name) > math.MaxUint16 {
+			return fmt.Errorf("parameter name %s is too long", name[:32])
+		}
+		if n := len(v.W) + len(v.Half); n != v.Rows*v.Columns {
+			return fmt.Errorf("parameter %s has %d weights but shape %dx%d", name, n, v.Rows, v.Columns)
+		}
+	}
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	index, headerSize := modelIndex(m, dtype)
+
+	var scratch [8]byte
+	le := binary.LittleEndian
+	bw.WriteString(ModelMagic)
+	le.PutUint32(scratch[:], ModelVersion)
+	bw.Write(scratch[:4])
+	le.PutUint32(scratch[:], uint32(len(index)))
+	bw.Write(scratch[:4])
+	for _, ti := range index {
+		le.PutUint16(scratch[:], uint16(len(ti.Name)))
+		bw.Write(scratch[:2])
+		bw.WriteString(ti.Name)
+		bw.WriteByte(byte(ti.DType))
+		le.PutUint32(scratch[:], uint32(ti.Rows))
+		bw.Write(scratch[:4])
+		le.PutUint32(scratch[:], uint32(ti.Columns))
+		bw.Write(scratch[:4])
+		le.PutUint64(scratch[:], uint64(ti.Offset))
+		bw.Write(scratch[:8])
+	}
+	written := headerSize
+	payloadStart := align(headerSize)
+	for _, ti := range index {
+		for ; written < payloadStart+ti.Offset; written++ {
+			bw.WriteByte(0)
+		}
+		v := m[ti.Name]
+		if ti.DType == Float32 {
+			for _, f := range v.weights() {
+				le.PutUint32(scratch[:], math.Float32bits(f))
+				bw.Write(scratch[:4])
+			}
+		} else {
+			half := v.Half
+			if half == nil || v.HalfType != ti.DType {
+				half = make([]uint16, ti.Rows*ti.Columns)
+				narrow(half, v.weights(), ti.DType)
+			}
+			for _, h := range half {
+				le.PutUint16(scratch[:], h)
+				bw.Write(scratch[:2])
+			}
+		}
+		written += ti.Size()
+	}
+	if e := bw.Flush(); e != nil {
+		return e
+	}
+	le.PutUint32(scratch[:], crc.Sum32())
+	_, e := w.Write(scratch[:4])
+	return e
+}
+
+// modelReader reads model file sequentially keeping track of position and checksum
+type modelReader struct {
+	r        io.Reader
+	crc      hash.Hash32
+	position int64
+	length   int64 // stream length if known, -1 otherwise
+	buffer   []byte
+}
+
+// newModelReader finds out length of in memory readers and files, it bounds sizes read from header
+func newModelReader(r io.Reader) *modelReader {
+	mr := &modelReader{r: r, crc: crc32.NewIEEE(), length: -1}
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		mr.length = int64(v.Len())
+	case *os.File:
+		if info, e := v.Stat(); e == nil && info.Mode().IsRegular() {
+			if position, e := v.Seek(0, io.SeekCurrent); e == nil {
+				mr.length = info.Size() - position
+			}
+		}
+	}
+	return mr
+}
+
+func (mr *modelReader) read(n int) ([]byte, error) {
+	if cap(mr.buffer) < n {
+		mr.buffer = make([]byte, n)
+	}
+	b := mr.buffer[:n]
+	if _, e := io.ReadFull(mr.r, b); e != nil {
+		return nil, e
+	}
+	mr.crc.Write(b)
+	mr.position += int64(n)
+	return b, nil
+}
+
+func (mr *modelReader) skipTo(position int64) error {
+	if position < mr.position {
+		return fmt.Errorf("broken model file, tensor at %d overlaps previous data at %d", position, mr.position)
+	}
+	n, e := io.CopyN(mr.crc, mr.r, position-mr.position)
+	mr.position += n
+	if e == io.EOF {
+		e = io.ErrUnexpectedEOF
+	}
+	return e
+}
+
+// readChunk is size of tensor data read at once, memory for tensor of stream of unknown length
+// grows with data actually read so broken size in header can not exhaust memory
+const readChunk = 1 << 20
+
+// readTensor reads float32 weights or half precision ones of tensor
+func (mr *modelReader) readTensor(ti *TensorInfo) (w []float32, half []uint16, e error) {
+	le := binary.LittleEndian
+	n, size := ti.Rows*ti.Columns, ti.DType.Size()
+	capacity := n
+	if mr.length < 0 && capacity > readChunk {
+		capacity = readChunk
+	}
+	if ti.DType == Float32 {
+		w = make([]float32, 0, capacity)
+	} else {
+		half = make([]uint16, 0, capacity)
+	}
+	for done := 0; done < n; {
+		k := n - done
+		if k > readChunk/size {
+			k = readChunk / size
+		}
+		b, e := mr.read(k * size)
+		if e != nil {
+			return nil, nil, e
+		}
+		for i := 0; i < k; i++ {
+			if ti.DType == Float32 {
+				w = append(w, math.Float32frombits(le.Uint32(b[4*i:])))
+			} else {
+				half = append(half, le.Uint16(b[2*i:]))
+			}
+		}
+		done += k
+	}
+	return
+}
+
+// maxInt is the largest int, number of weights of tensor must fit it
+const maxInt = int(^uint(0) >> 1)
+
+// readModelHeader reads magic, version and tensor index, sizes are checked against
+// stream length when it is known
+func (mr *modelReader) readHeader() ([]*TensorInfo, error) {
+	le := binary.LittleEndian
+	b, e := mr.read(12)
+	if e != nil {
+		return nil, e
+	}
+	if string(b[:4]) != ModelMagic {
+		return nil, fmt.Errorf("not a binary model, magic %q", b[:4])
+	}
+	if version := le.Uint32(b[4:]); version != ModelVersion {
+		return nil, fmt.Errorf("unsupported binary model version %d", version)
+	}
+	count := int64(le.Uint32(b[8:]))
+	// every index entry takes at least 19 bytes
+	if mr.length >= 0 && count*19 > mr.length-mr.position {
+		return nil, fmt.Errorf("broken model file, index of %d tensors does not fit %d bytes", count, mr.length)
+	}
+	var index []*TensorInfo
+	for i := int64(0); i < count; i++ {
+		if b, e = mr.read(2); e != nil {
+			return nil, e
+		}
+		if b, e = mr.read(int(le.Uint16(b)) + 1 + 4 + 4 + 8); e != nil {
+			return nil, e
+		}
+		n := len(b) - 17
+		ti := &TensorInfo{Name: string(b[:n]), DType: DType(b[n])}
+		rows, columns := uint64(le.Uint32(b[n+1:])), uint64(le.Uint32(b[n+5:]))
+		offset := le.Uint64(b[n+9:])
+		if !ti.DType.valid() {
+			return nil, fmt.Errorf("tensor %s has unsupported dtype %s", ti.Name, ti.DType)
+		}
+		// product of two uint32 fits uint64
+		if rows*columns > uint64(maxInt/ti.DType.Size()) {
+			return nil, fmt.Errorf("tensor %s of %dx%d is too large", ti.Name, rows, columns)
+		}
+		if offset > math.MaxInt64/2 {
+			return nil, fmt.Errorf("tensor %s has broken offset %d", ti.Name, offset)
+		}
+		ti.Rows, ti.Columns, ti.Offset = int(rows), int(columns), int64(offset)
+		index = append(index, ti)
+	}
+	if mr.length >= 0 {
+		// payload and checksum must fit stream
+		payload := mr.length - align(mr.position) - 4
+		for _, ti := range index {
+			if ti.Size() > payload || ti.Offset > payload-ti.Size() {
+				return nil, fmt.Errorf("broken model file, tensor %s of %d bytes at %d is out of %d bytes of payload", ti.Name, ti.Size(), ti.Offset, payload)
+			}
+		}
+	}
+	return index, nil
+}
+
+// ReadModelBinary reads model written by WriteModelBinary verifying checksum,
+// half precision tensors are widened to float32
+func ReadModelBinary(r io.Reader) (map[string]*Matrix, error) {
+	return readModelBinary(r, false)
+}
+
+// ReadModelBinaryCompact reads model keeping half precision matrices compressed, see CompressModel
+func ReadModelBinaryCompact(r io.Reader) (map[string]*Matrix, error) {
+	return readModelBinary(r, true)
+}
+
+func readModelBinary(r io.Reader, compact bool) (map[string]*Matrix, error) {
+	mr := newModelReader(r)
+	index, e := mr.readHeader()
+	if e != nil {
+		return nil, e
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Offset < index[j].Offset })
+	payloadStart := align(mr.position)
+	m := make(map[string]*Matrix, len(index))
+	for _, ti := range index {
+		if e = mr.skipTo(payloadStart + ti.Offset); e != nil {
+			return nil, e
+		}
+		w, half, e := mr.readTensor(ti)
+		if e != nil {
+			return nil, fmt.Errorf("tensor %s: %v", ti.Name, e)
+		}
+		matrix := &Matrix{Rows: ti.Rows, Columns: ti.Columns, W: w}
+		if half != nil {
+			if compact && ti.Columns > 1 {
+				matrix.Half, matrix.HalfType = half, ti.DType
+			} else {
+				matrix.W = make([]float32, len(half))
+				widen(matrix.W, half, ti.DType)
+			}
+		}
+		m[ti.Name] = matrix
+	}
+	sum := mr.crc.Sum32()
+	var trailer [4]byte
+	if _, e = io.ReadFull(r, trailer[:]); e != nil {
+		return nil, fmt.Errorf("model checksum is missing: %v", e)
+	}
+	if stored := binary.LittleEndian.Uint32(trailer[:]); stored != sum {
+		return nil, fmt.Errorf("model checksum mismatch %08x != %08x", stored, sum)
+	}
+	return m, nil
+}
+
+// IsBinaryModel reports if file starts with binary model magic
+func IsBinaryModel(name string) bool {
+	f, e := os.Open(name)
+	if e != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, len(ModelMagic))
+	if _, e = io.ReadFull(f, magic); e != nil {
+		return false
+	}
+	return string(magic) == ModelMagic
+}
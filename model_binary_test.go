@@ -0,0 +1,91 @@
+package gortex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinaryModel(t *testing.T) {
+	model := map[string]*Matrix{
+		"Encoder_Wf": RandMat(7, 5),
+		"Encoder_Bf": RandMat(7, 1),
+		"L0w":        RandMat(1, 1),
+	}
+	dir := t.TempDir()
+	for _, save := range []func(string, map[string]*Matrix) error{SaveModel, SaveModelJSON} {
+		name := filepath.Join(dir, "model")
+		if e := save(name, model); e != nil {
+			t.Fatal(e)
+		}
+		loaded, e := LoadModel(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(loaded) != len(model) {
+			t.Fatalf("loaded %d parameters but %d saved", len(loaded), len(model))
+		}
+		for k, v := range model {
+			l := loaded[k]
+			if l.Rows != v.Rows || l.Columns != v.Columns || len(l.W) != len(v.W) {
+				t.Fatalf("parameter %s shape mismatch", k)
+			}
+			for i := range v.W {
+				if l.W[i] != v.W[i] {
+					t.Fatalf("parameter %s weight %d mismatch", k, i)
+				}
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	if e := WriteModelBinary(&buffer, model); e != nil {
+		t.Fatal(e)
+	}
+	// corrupt one weight
+	data := buffer.Bytes()
+	data[len(data)-8] ^= 1
+	if _, e := ReadModelBinary(bytes.NewReader(data)); e == nil {
+		t.Fatal("corrupted model must fail checksum")
+	}
+	if _, e := ReadModelBinary(bytes.NewReader(data[:len(data)/2])); e == nil {
+		t.Fatal("truncated model must fail")
+	}
+}
+
+func TestBinaryModelBrokenHeader(t *testing.T) {
+	header := func(count, rows, columns uint32, offset uint64) []byte {
+		var b bytes.Buffer
+		b.WriteString(ModelMagic)
+		binary.Write(&b, binary.LittleEndian, uint32(ModelVersion))
+		binary.Write(&b, binary.LittleEndian, count)
+		binary.Write(&b, binary.LittleEndian, uint16(1))
+		b.WriteString("W")
+		b.WriteByte(byte(Float32))
+		binary.Write(&b, binary.LittleEndian, rows)
+		binary.Write(&b, binary.LittleEndian, columns)
+		binary.Write(&b, binary.LittleEndian, offset)
+		b.Write(make([]byte, 128))
+		return b.Bytes()
+	}
+	for _, c := range []struct {
+		name string
+		file []byte
+	}{
+		{"huge count", header(math.MaxUint32, 1, 1, 0)},
+		{"overflowing size", header(1, math.MaxUint32, math.MaxUint32, 0)},
+		{"huge size", header(1, 1<<30, 1<<20, 0)},
+		{"huge offset", header(1, 1, 1, math.MaxUint64)},
+		{"tensor out of file", header(1, 1, 1<<10, 0)},
+	} {
+		// stream of known length is checked before reading data, one of unknown length fails on its end
+		for _, r := range []io.Reader{bytes.NewReader(c.file), io.MultiReader(bytes.NewReader(c.file))} {
+			if _, e := ReadModelBinary(r); e == nil {
+				t.Fatalf("%s must be reported", c.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package gortex
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"unsafe"
+)
+
+// MappedModel is read-only model which weights point directly into memory mapped binary model file,
+// processes mapping the same file share one copy of weights through the page cache
+type MappedModel struct {
+	Parameters map[string]*Matrix
+	Index      []*TensorInfo
+	data       []byte
+	unmap      func([]byte) error
+}
+
+func littleEndianHost() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}
+
+// wrap makes matrices over model file bytes without copying weights
+func (mm *MappedModel) wrap(verify bool) error {
+	if !littleEndianHost() {
+		return fmt.Errorf("memory mapped models need little endian host")
+	}
+	mr := newModelReader(bytes.NewReader(mm.data))
+	index, e := mr.readHeader()
+	if e != nil {
+		return e
+	}
+	payloadStart := align(mr.position)
+	mm.Index = index
+	mm.Parameters = make(map[string]*Matrix, len(index))
+	for _, ti := range index {
+		start := payloadStart + ti.Offset
+		end := start + ti.Size()
+		if start%ModelAlignment != 0 || end > int64(len(mm.data))-4 {
+			return fmt.Errorf("tensor %s at [%d:%d] is out of file bounds or unaligned", ti.Name, start, end)
+		}
+		m := &Matrix{Rows: ti.Rows, Columns: ti.Columns}
+		if n := ti.Rows * ti.Columns; n > 0 {
+			if ti.DType == Float32 {
+				m.W = unsafe.Slice((*float32)(unsafe.Pointer(&mm.data[start])), n)
+			} else {
+				half := unsafe.Slice((*uint16)(unsafe.Pointer(&mm.data[start])), n)
+				if ti.Columns > 1 { // half precision matrices stay compressed, see CompressModel
+					m.Half, m.HalfType = half, ti.DType
+				} else {
+					m.W = make([]float32, n)
+					widen(m.W, half, ti.DType)
+				}
+			}
+		}
+		mm.Parameters[ti.Name] = m
+	}
+	if verify {
+		body := mm.data[:len(mm.data)-4]
+		trailer := mm.data[len(mm.data)-4:]
+		stored := uint32(trailer[0]) | uint32(trailer[1])<<8 | uint32(trailer[2])<<16 | uint32(trailer[3])<<24
+		if sum := crc32.ChecksumIEEE(body); sum != stored {
+			return fmt.Errorf("model checksum mismatch %08x != %08x", stored, sum)
+		}
+	}
+	return nil
+}
+
+// Close releases mapping, parameters must not be used after
+func (mm *MappedModel) Close() error {
+	if mm.data == nil {
+		return nil
+	}
+	mm.Parameters = nil
+	data := mm.data
+	mm.data = nil
+	if mm.unmap != nil {
+		return mm.unmap(data)
+	}
+	return nil
+}
+
+// MakeTrainable copies weights into writable memory and allocates gradients
+// for parameters loaded without them (memory mapped, json or binary loaded models),
+// compressed matrices are decompressed
+func MakeTrainable(model map[string]*Matrix) {
+	for _, m := range model {
+		m.Decompress()
+		if m.DW == nil {
+			w := make([]float32, len(m.W))
+			copy(w, m.W)
+			m.W = w
+			m.DW = Zeros(len(m.W))
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//+build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package gortex
+
+import "io/ioutil"
+
+// MapModel reads binary model file into memory on platforms without mmap
+func MapModel(name string, verify bool) (*MappedModel, error) {
+	data, e := ioutil.ReadFile(name)
+	if e != nil {
+		return nil, e
+	}
+	mm := &MappedModel{data: data}
+	if e = mm.wrap(verify); e != nil {
+		return nil, e
+	}
+	return mm, nil
+}
@@ -0,0 +1,46 @@
+package gortex
+
+import (
+	"path/filepath"
+	"testing"
+	"unsafe"
+)
+
+func TestMapModel(t *testing.T) {
+	model := map[string]*Matrix{"W": RandMat(33, 17), "b": RandMat(33, 1)}
+	name := filepath.Join(t.TempDir(), "model")
+	if e := SaveModel(name, model); e != nil {
+		t.Fatal(e)
+	}
+	mm, e := MapModel(name, true)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer mm.Close()
+	for k, v := range model {
+		m := mm.Parameters[k]
+		if m.Rows != v.Rows || m.Columns != v.Columns || m.DW != nil {
+			t.Fatalf("mapped parameter %s has wrong shape or gradient", k)
+		}
+		if uintptr(unsafe.Pointer(&m.W[0]))%ModelAlignment != 0 {
+			t.Fatalf("mapped parameter %s is not aligned", k)
+		}
+		for i := range v.W {
+			if m.W[i] != v.W[i] {
+				t.Fatalf("mapped parameter %s weight %d mismatch", k, i)
+			}
+		}
+	}
+	// inference works straight from mapping
+	G := &Graph{}
+	y := G.Add(G.Mul(mm.Parameters["W"], RandMat(17, 1)), mm.Parameters["b"])
+	if y.Rows != 33 {
+		t.Fatal("wrong output size")
+	}
+	MakeTrainable(mm.Parameters)
+	W := mm.Parameters["W"]
+	if W.DW == nil || len(W.DW) != len(W.W) {
+		t.Fatal("gradients must be allocated")
+	}
+	W.W[0] += 1 // writable copy
+}
@@ -0,0 +1,37 @@
+//+build linux darwin freebsd netbsd openbsd
+
+package gortex
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// MapModel memory maps binary model file read-only, verify checks file checksum
+// which touches every page of the file
+func MapModel(name string, verify bool) (*MappedModel, error) {
+	f, e := os.Open(name)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	info, e := f.Stat()
+	if e != nil {
+		return nil, e
+	}
+	size := info.Size()
+	if size < int64(len(ModelMagic)) || size != int64(int(size)) {
+		return nil, fmt.Errorf("model file %s has unsupported size %d", name, size)
+	}
+	data, e := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if e != nil {
+		return nil, e
+	}
+	mm := &MappedModel{data: data, unmap: syscall.Munmap}
+	if e = mm.wrap(verify); e != nil {
+		mm.Close()
+		return nil, e
+	}
+	return mm, nil
+}
@@ -161,5 +161,7 @@ func (tc *DilatedSeq2seq) CollectGradient(clone *DilatedSeq2seq) {
 	for k, v := range parameters {
 		assembler.Sxpy(cloneParameters[k].DW, v.DW)
 		assembler.Sclean(cloneParameters[k].DW)
+		v.MergeTouched(cloneParameters[k])
+		cloneParameters[k].ResetTouched()
 	}
 }
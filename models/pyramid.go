@@ -28,6 +28,15 @@ type PyramidClassifier struct {
 	Updates        map[string]float32          `json:"-"`
 	Classes        map[string]*struct{ Index, Count uint }
 	forward_count  uint
+	attention      map[int]float32 // gates of token pairs of last Forward
+	attended       map[int]string  // token pairs of last Forward
+}
+
+var _ Classifier = (*PyramidClassifier)(nil)
+
+// Attention returns gates of input token pairs of last Forward, pairs are keyed by position
+func (p *PyramidClassifier) Attention() (map[int]float32, map[int]string) {
+	return p.attention, p.attended
 }
 
 func (p *PyramidClassifier) GetActiveParameters() map[string]*g.Matrix {
@@ -100,11 +109,14 @@ func (p *PyramidClassifier) GetName() string {
 	return fmt.Sprintf("pyramid.ft.l%d.e%d.h%d.c%d.json", p.Height, p.EmbeddingSize, p.HiddenSize, len(p.Classes))
 }
 
-func (p PyramidClassifier) Create() (*PyramidClassifier, error) {
+// Create makes parameters of classifier, options override Xavier initialization of weights and biases,
+// begin and end of sequence vectors are biases too
+func (p PyramidClassifier) Create(options ...g.InitOption) (*PyramidClassifier, error) {
 	// check height is valid
 	if p.Height < 1 {
 		return nil, fmt.Errorf("pyramid height must be > 0 but %d was given", p.Height)
 	}
+	in := g.NewInitialization(g.XavierNormal{}, g.XavierNormal{}, g.XavierNormal{}, options...)
 	// create pyramid levels
 	p.Levels = make([]*g.Matrix, p.Height)
 	p.Biases = make([]*g.Matrix, p.Height)
@@ -112,18 +124,18 @@ func (p PyramidClassifier) Create() (*PyramidClassifier, error) {
 	p.Updates = make(map[string]float32)
 	for l := range make([]struct{}, p.Height) {
 		if l == 0 { // input layer
-			p.Levels[l] = g.RandXavierMat(p.HiddenSize, 2*p.EmbeddingSize)
+			p.Levels[l] = g.InitMat(in.Weights, p.HiddenSize, 2*p.EmbeddingSize)
 		} else { // hidden layer
-			p.Levels[l] = g.RandXavierMat(p.HiddenSize, 2*p.HiddenSize)
+			p.Levels[l] = g.InitMat(in.Weights, p.HiddenSize, 2*p.HiddenSize)
 		}
-		p.Biases[l] = g.RandXavierMat(p.HiddenSize, 1)
+		p.Biases[l] = g.InitMat(in.Biases, p.HiddenSize, 1)
 	}
-	p.Gate = g.RandXavierMat(1, p.HiddenSize)
-	p.Who = g.RandXavierMat(len(p.Classes), p.HiddenSize)
-	p.Bos = g.RandXavierMat(p.EmbeddingSize, 1)
-	p.Eos = g.RandXavierMat(p.EmbeddingSize, 1)
+	p.Gate = g.InitMat(in.Weights, 1, p.HiddenSize)
+	p.Who = g.InitMat(in.Weights, len(p.Classes), p.HiddenSize)
+	p.Bos = g.InitMat(in.Biases, p.EmbeddingSize, 1)
+	p.Eos = g.InitMat(in.Biases, p.EmbeddingSize, 1)
 
-	p.HEos = g.RandXavierMat(p.HiddenSize, 1)
+	p.HEos = g.InitMat(in.Biases, p.HiddenSize, 1)
 
 	p.Parameters = map[string]*g.Matrix{
 		"Bos":  p.Bos,
@@ -174,6 +186,7 @@ func (p *PyramidClassifier) Forward(G *g.Graph, tokens []string) (class uint, co
 		embedings[t].W = p.Embed(token)
 	}
 	var paddedTokens []string
+	p.attention, p.attended = make(map[int]float32), make(map[int]string)
 	// propagate layer by layer
 
 	var story []string
@@ -196,6 +209,10 @@ func (p *PyramidClassifier) Forward(G *g.Graph, tokens []string) (class uint, co
 			p.Updates[fmt.Sprintf("L%db", l)]++
 			p.Updates["Gate"]++
 			newEmbedings = append(newEmbedings, G.EMul(gate, conv))
+			if l == 0 {
+				p.attention[t] = gate.W[0]
+				p.attended[t] = paddedTokens[2*t] + " " + paddedTokens[2*t+1]
+			}
 			if p.forward_count%1000 == 0 && l == 0 {
 				story = append(story, fmt.Sprintf("%d %s -> %f", len(story), paddedTokens[2*t]+" "+paddedTokens[2*t+1], gate.W[0]))
 			}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	g "github.com/vseledkin/gortex"
+)
+
+// PyramidConfig is PyramidClassifier constructor arguments kept in model bundles,
+// Embed function is not stored and must be set after loading
+type PyramidConfig struct {
+	Height        int
+	EmbeddingSize int
+	HiddenSize    int
+	Name          string
+	Classes       map[string]*struct{ Index, Count uint }
+}
+
+func init() {
+	g.RegisterArchitecture("PyramidClassifier", func(config json.RawMessage) (interface{}, map[string]*g.Matrix, error) {
+		c := new(PyramidConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("PyramidClassifier config: %v", e)
+		}
+		p, e := PyramidClassifier{Height: c.Height, EmbeddingSize: c.EmbeddingSize, HiddenSize: c.HiddenSize, Name: c.Name, Classes: c.Classes}.Create()
+		if e != nil {
+			return nil, nil, e
+		}
+		return p, p.Parameters, nil
+	})
+}
+
+// LoadClassifier loads bundle of registered classifier, embed is set to classifiers which
+// embedding function can not be stored in bundle
+func LoadClassifier(name string, embed func(text string) []float32) (Classifier, *g.Bundle, error) {
+	b, e := g.LoadBundle(name)
+	if e != nil {
+		return nil, nil, e
+	}
+	c, ok := b.Model.(Classifier)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is %s model but not classifier", name, b.Architecture)
+	}
+	if p, ok := c.(*PyramidClassifier); ok {
+		if embed == nil {
+			return nil, nil, fmt.Errorf("%s needs embedding function", b.Architecture)
+		}
+		p.Embed = embed
+	}
+	return c, b, nil
+}
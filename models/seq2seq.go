@@ -146,5 +146,7 @@ func (tc *Seq2seq) CollectGradient(clone *Seq2seq) {
 	for k, v := range parameters {
 		assembler.Sxpy(cloneParameters[k].DW, v.DW)
 		assembler.Sclean(cloneParameters[k].DW)
+		v.MergeTouched(cloneParameters[k])
+		cloneParameters[k].ResetTouched()
 	}
 }
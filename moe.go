@@ -0,0 +1,273 @@
+package gortex
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+/*
+	Outrageously Large Neural Networks: The Sparsely-Gated Mixture-of-Experts Layer https://arxiv.org/abs/1701.06538
+	Noam Shazeer, Azalia Mirhoseini, Krzysztof Maziarz, Andy Davis, Quoc Le, Geoffrey Hinton, Jeff Dean
+
+	only K experts selected by noisy gating network are run for every input,
+	load balancing loss is one of Switch Transformer https://arxiv.org/abs/2101.03961
+*/
+
+type MoE struct {
+	Experts []*MLP
+	Wg      *Matrix // gating weights
+	Wn      *Matrix // weights of gating noise scale, noise is added during training only
+	K       int
+
+	Updates       []float32 `json:"-"` // number of training inputs routed to every expert since last GetActiveParameters
+	probabilities []*Matrix // probabilities of all experts of training inputs given to Forward on graph
+	selection     []float32 // number of training inputs routed to every expert on graph
+	graph         *Graph    // graph routing for BalanceLoss is collected on, new graph starts collection again
+}
+
+// MakeMoE makes experts MLPs of sizes x_size, h_size, out_size, K of them run for every input,
+// gating weights start from zero as in paper so experts are chosen by noise at start of training,
+// options are applied to experts
+func MakeMoE(x_size, h_size, out_size, experts, k int, activation string, options ...InitOption) *MoE {
+	if k < 1 || k > experts {
+		panic(fmt.Errorf("MoE can select from 1 to %d experts but %d given", experts, k))
+	}
+	moe := &MoE{K: k, Wg: Mat(experts, x_size), Wn: Mat(experts, x_size)}
+	for i := 0; i < experts; i++ {
+		moe.Experts = append(moe.Experts, MakeMLP([]int{x_size, h_size, out_size}, activation, 0, false, options...))
+	}
+	return moe
+}
+
+func (moe *MoE) GetParameters(namespace string) map[string]*Matrix {
+	p := map[string]*Matrix{
+		namespace + "_Wg": moe.Wg,
+		namespace + "_Wn": moe.Wn,
+	}
+	for i, expert := range moe.Experts {
+		for k, v := range expert.GetParameters(fmt.Sprintf("%s_expert%d", namespace, i)) {
+			p[k] = v
+		}
+	}
+	return p
+}
+
+func (moe *MoE) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(moe.GetParameters(namespace), parameters)
+}
+
+// GetActiveParameters returns parameters of gating network and experts used since last call,
+// pass them to optimizer so experts not used are not updated
+func (moe *MoE) GetActiveParameters(namespace string) map[string]*Matrix {
+	p := map[string]*Matrix{
+		namespace + "_Wg": moe.Wg,
+		namespace + "_Wn": moe.Wn,
+	}
+	for i, expert := range moe.Experts {
+		if i < len(moe.Updates) && moe.Updates[i] > 0 {
+			for k, v := range expert.GetParameters(fmt.Sprintf("%s_expert%d", namespace, i)) {
+				p[k] = v
+			}
+			moe.Updates[i] = 0
+		}
+	}
+	return p
+}
+
+// softmaxOver is softmax of m elements at given positions, other probabilities are zero
+func softmaxOver(g *Graph, m *Matrix, positions []int) *Matrix {
+	out := Mat(m.Rows, 1)
+	maxval := float32(math.Inf(-1))
+	for _, i := range positions {
+		if m.W[i] > maxval {
+			maxval = m.W[i]
+		}
+	}
+	var sum float32
+	for _, i := range positions {
+		out.W[i] = float32(math.Exp(float64(m.W[i] - maxval)))
+		sum += out.W[i]
+	}
+	for _, i := range positions {
+		out.W[i] /= sum
+	}
+	if g.NeedsBackprop {
+		g.backprop = append(g.backprop, func() {
+			var dot float32
+			for _, i := range positions {
+				dot += out.W[i] * out.DW[i]
+			}
+			for _, i := range positions {
+				m.DW[i] += out.W[i] * (out.DW[i] - dot)
+			}
+		})
+	}
+	return out
+}
+
+// softplus is log(1 + exp(x)) noise scale
+func softplus(g *Graph, m *Matrix) *Matrix {
+	out := Mat(m.Rows, m.Columns)
+	for i, v := range m.W {
+		out.W[i] = float32(math.Log1p(math.Exp(float64(v))))
+	}
+	if g.NeedsBackprop {
+		g.backprop = append(g.backprop, func() {
+			for i, v := range m.W {
+				m.DW[i] += out.DW[i] / float32(1+math.Exp(-float64(v)))
+			}
+		})
+	}
+	return out
+}
+
+// combine is sum of expert outputs weighted by their gates
+func combine(g *Graph, gates *Matrix, selected []int, outputs []*Matrix) *Matrix {
+	out := Mat(outputs[0].Rows, 1)
+	for k, i := range selected {
+		for j, v := range outputs[k].W {
+			out.W[j] += gates.W[i] * v
+		}
+	}
+	if g.NeedsBackprop {
+		g.backprop = append(g.backprop, func() {
+			for k, i := range selected {
+				for j, v := range outputs[k].W {
+					outputs[k].DW[j] += gates.W[i] * out.DW[j]
+					gates.DW[i] += v * out.DW[j]
+				}
+			}
+		})
+	}
+	return out
+}
+
+// Step routes x to K experts, returns mixture of their outputs, probabilities of all experts
+// made by clean gating logits and indexes of selected experts. Gates of K experts are softmax
+// of their logits, single expert gets its probability among all experts as in Switch Transformer,
+// otherwise its gate is always 1 and gating network gets no gradient from task loss
+func (moe *MoE) Step(g *Graph, x *Matrix) (y, probabilities *Matrix, selected []int) {
+	clean := g.Mul(moe.Wg, x)
+	logits := clean
+	if g.NeedsBackprop {
+		noise := RandMat(clean.Rows, 1)
+		logits = g.Add(clean, g.EMul(noise, softplus(g, g.Mul(moe.Wn, x))))
+	}
+	// select K largest noisy logits
+	order := make([]int, logits.Rows)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return logits.W[order[a]] > logits.W[order[b]] })
+	selected = order[:moe.K]
+	sort.Ints(selected)
+
+	all := make([]int, clean.Rows)
+	for i := range all {
+		all[i] = i
+	}
+	probabilities = softmaxOver(g, clean, all)
+
+	gated := selected
+	if moe.K == 1 {
+		gated = all
+	}
+	gates := softmaxOver(g, logits, gated)
+	outputs := make([]*Matrix, len(selected))
+	for k, i := range selected {
+		outputs[k] = moe.Experts[i].Forward(g, x)
+	}
+	y = combine(g, gates, selected, outputs)
+
+	if g.NeedsBackprop {
+		if len(moe.Updates) != len(moe.Experts) {
+			moe.Updates = make([]float32, len(moe.Experts))
+		}
+		for _, i := range selected {
+			moe.Updates[i]++
+		}
+	}
+	return
+}
+
+// Forward runs Step remembering routing of training inputs for BalanceLoss of the same graph
+func (moe *MoE) Forward(g *Graph, x *Matrix) *Matrix {
+	y, probabilities, selected := moe.Step(g, x)
+	if g.NeedsBackprop {
+		if moe.graph != g {
+			moe.graph, moe.probabilities = g, nil
+			moe.selection = make([]float32, len(moe.Experts))
+		}
+		for _, i := range selected {
+			moe.selection[i]++
+		}
+		moe.probabilities = append(moe.probabilities, probabilities)
+	}
+	return y
+}
+
+// BalanceLoss is load balancing loss of inputs passed to Forward on g since last call,
+// weight * experts * sum of fraction of inputs routed to expert times its mean probability,
+// it is 1*weight when load is uniform, gradient goes to gating network
+func (moe *MoE) BalanceLoss(g *Graph, weight float32) (cost float32) {
+	if moe.graph != g || len(moe.probabilities) == 0 {
+		return 0
+	}
+	routings, selection := moe.probabilities, moe.selection
+	moe.probabilities, moe.selection, moe.graph = nil, nil, nil
+
+	n := float32(len(moe.Experts))
+	inputs := float32(len(routings))
+	for i := range selection {
+		var mean float32
+		for _, r := range routings {
+			mean += r.W[i]
+		}
+		cost += selection[i] / (inputs * float32(moe.K)) * mean / inputs
+	}
+	cost *= weight * n
+	if g.NeedsBackprop {
+		g.backprop = append(g.backprop, func() {
+			for i := range selection {
+				d := weight * n * selection[i] / (inputs * float32(moe.K)) / inputs
+				for _, r := range routings {
+					r.DW[i] += d
+				}
+			}
+		})
+	}
+	return
+}
+
+// MoEConfig makes MoE under MoE architecture name
+type MoEConfig struct {
+	Namespace  string // parameters namespace, MoE if empty
+	Input      int
+	Hidden     int
+	Output     int
+	Experts    int
+	K          int
+	Activation string
+}
+
+func init() {
+	RegisterArchitecture("MoE", func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(MoEConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("MoE config: %v", e)
+		}
+		if _, e := ActivationByName(c.Activation); e != nil {
+			return nil, nil, fmt.Errorf("MoE config: %v", e)
+		}
+		if c.K < 1 || c.K > c.Experts {
+			return nil, nil, fmt.Errorf("MoE config: can select from 1 to %d experts but %d given", c.Experts, c.K)
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = "MoE"
+		}
+		moe := MakeMoE(c.Input, c.Hidden, c.Output, c.Experts, c.K, c.Activation)
+		return moe, moe.GetParameters(c.Namespace), nil
+	})
+}
@@ -0,0 +1,117 @@
+package gortex
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/vseledkin/gortex/assembler"
+)
+
+func TestMoEGating(t *testing.T) {
+	logits, outputs := RandMat(5, 1), []*Matrix{RandMat(3, 1), RandMat(3, 1)}
+	selected := []int{1, 3}
+	weights := RandMat(3, 1)
+	loss := func() float32 {
+		G := &Graph{}
+		out := combine(G, softmaxOver(G, logits, selected), selected, outputs)
+		var l float32
+		for i := range out.W {
+			l += out.W[i] * weights.W[i]
+		}
+		return l
+	}
+	G := &Graph{NeedsBackprop: true}
+	gates := softmaxOver(G, logits, selected)
+	if gates.W[0] != 0 || math.Abs(float64(gates.W[1]+gates.W[3]-1)) > 1e-6 {
+		t.Fatal("gates must be distribution over selected experts")
+	}
+	out := combine(G, gates, selected, outputs)
+	copy(out.DW, weights.W)
+	G.Backward()
+	if logits.DW[0] != 0 || logits.DW[2] != 0 || logits.DW[4] != 0 {
+		t.Fatal("not selected logits must have no gradient")
+	}
+	for _, m := range []*Matrix{logits, outputs[0], outputs[1]} {
+		for i := range m.W {
+			v := m.W[i]
+			m.W[i] = v + 1e-2
+			plus := loss()
+			m.W[i] = v - 1e-2
+			minus := loss()
+			m.W[i] = v
+			numeric := (plus - minus) / 2e-2
+			if math.Abs(float64(numeric-m.DW[i])) > 1e-2*math.Max(1, math.Abs(float64(numeric))) {
+				t.Fatalf("gradient %d is %f but numeric one is %f", i, m.DW[i], numeric)
+			}
+		}
+	}
+}
+
+func TestMoE(t *testing.T) {
+	moe := MakeMoE(4, 6, 3, 4, 1, "Tanh")
+	G := &Graph{NeedsBackprop: true}
+	y, _, selected := moe.Step(G, RandMat(4, 1))
+	if y.Rows != 3 || len(selected) != 1 {
+		t.Fatal("wrong MoE output")
+	}
+	// single expert gate is its probability so task loss trains gating network
+	assembler.Sset(1, y.DW)
+	G.Backward()
+	var gating float32
+	for _, v := range moe.Wg.DW {
+		gating += float32(math.Abs(float64(v)))
+	}
+	if gating == 0 {
+		t.Fatal("gating weights must get gradient from task loss")
+	}
+	for _, m := range moe.GetActiveParameters("moe") {
+		m.DW = make([]float32, len(m.DW))
+	}
+
+	// inference does not count updates and routing of previous graph is dropped
+	moe.Forward(&Graph{}, RandMat(4, 1))
+	for i, u := range moe.Updates {
+		if u != 0 {
+			t.Fatalf("expert %d is counted as updated during inference", i)
+		}
+	}
+	moe.Forward(&Graph{NeedsBackprop: true}, RandMat(4, 1))
+	// uniform gating makes balance loss equal to its weight
+	G = &Graph{NeedsBackprop: true}
+	for i := 0; i < 5; i++ {
+		moe.Forward(G, RandMat(4, 1))
+	}
+	if cost := moe.BalanceLoss(G, 0.5); math.Abs(float64(cost-0.5)) > 1e-5 {
+		t.Fatalf("balance loss of uniform gating is %f instead of 0.5", cost)
+	}
+	G.Backward()
+
+	// only experts selected get gradients and updates
+	active := moe.GetActiveParameters("moe")
+	used := 0
+	for i, expert := range moe.Experts {
+		_, isActive := active[fmt.Sprintf("moe_expert%d_layer0_W", i)]
+		var gradient float32
+		for _, v := range expert.Layers[0].W.DW {
+			gradient += float32(math.Abs(float64(v)))
+		}
+		if !isActive && gradient != 0 {
+			t.Fatalf("expert %d has gradient but was not selected", i)
+		}
+		if isActive {
+			used++
+		}
+	}
+	if used == 0 || active["moe_Wg"] == nil || len(moe.GetActiveParameters("moe")) != 2 {
+		t.Fatal("active parameters must be reset after they are taken")
+	}
+
+	b, e := NewBundle("MoE", &MoEConfig{Input: 4, Hidden: 6, Output: 3, Experts: 3, K: 2, Activation: "Tanh"})
+	if e != nil || len(b.Parameters) != 2+3*4 {
+		t.Fatalf("MoE is not registered properly: %v", e)
+	}
+	if _, e := NewBundle("MoE", &MoEConfig{Input: 4, Hidden: 6, Output: 3, Experts: 3, K: 4}); e == nil {
+		t.Fatal("K above number of experts must be reported")
+	}
+}
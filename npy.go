@@ -0,0 +1,260 @@
+package gortex
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NumPy format https://numpy.org/doc/stable/reference/generated/numpy.lib.format.html
+// matrices are written as C order float32 Rows x Columns arrays, vectors as 1d arrays
+
+const npyMagic = "\x93NUMPY"
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr':\s*'([<>|=])([a-z])(\d+)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape':\s*\(([\d,\s]*)\)`)
+)
+
+// WriteNpy writes matrix as version 1.0 npy array
+func WriteNpy(w io.Writer, m *Matrix) error {
+	if len(m.W) != m.Rows*m.Columns {
+		return fmt.Errorf("matrix has %d weights but shape %dx%d", len(m.W), m.Rows, m.Columns)
+	}
+	shape := fmt.Sprintf("(%d, %d)", m.Rows, m.Columns)
+	if m.Columns == 1 {
+		shape = fmt.Sprintf("(%d,)", m.Rows)
+	}
+	header := "{'descr': '<f4', 'fortran_order': False, 'shape': " + shape + ", }"
+	// magic, version and header length take 10 bytes, data starts at 64 byte boundary
+	padding := 64 - (10+len(header)+1)%64
+	if padding == 64 {
+		padding = 0
+	}
+	header += strings.Repeat(" ", padding) + "\n"
+	bw := bufio.NewWriter(w)
+	bw.WriteString(npyMagic)
+	bw.Write([]byte{1, 0})
+	var scratch [4]byte
+	binary.LittleEndian.PutUint16(scratch[:], uint16(len(header)))
+	bw.Write(scratch[:2])
+	bw.WriteString(header)
+	for _, v := range m.W {
+		binary.LittleEndian.PutUint32(scratch[:], math.Float32bits(v))
+		bw.Write(scratch[:])
+	}
+	return bw.Flush()
+}
+
+// ReadNpy reads float32 or float64 (downcast) npy array,
+// 1d arrays become vectors and arrays of higher rank are collapsed into Rows x last dimension
+func ReadNpy(r io.Reader) (*Matrix, error) {
+	var preamble [10]byte
+	if _, e := io.ReadFull(r, preamble[:8]); e != nil {
+		return nil, e
+	}
+	if string(preamble[:6]) != npyMagic {
+		return nil, fmt.Errorf("not a npy array, magic %q", preamble[:6])
+	}
+	var headerSize int
+	switch preamble[6] {
+	case 1:
+		if _, e := io.ReadFull(r, preamble[8:10]); e != nil {
+			return nil, e
+		}
+		headerSize = int(binary.LittleEndian.Uint16(preamble[8:]))
+	case 2, 3:
+		var size [4]byte
+		if _, e := io.ReadFull(r, size[:]); e != nil {
+			return nil, e
+		}
+		headerSize = int(binary.LittleEndian.Uint32(size[:]))
+	default:
+		return nil, fmt.Errorf("unsupported npy version %d.%d", preamble[6], preamble[7])
+	}
+	if headerSize > 100<<20 {
+		return nil, fmt.Errorf("npy header of %d bytes is too large", headerSize)
+	}
+	b := make([]byte, headerSize)
+	if _, e := io.ReadFull(r, b); e != nil {
+		return nil, e
+	}
+	header := string(b)
+
+	descr := npyDescr.FindStringSubmatch(header)
+	if descr == nil || descr[2] != "f" || (descr[3] != "4" && descr[3] != "8") {
+		return nil, fmt.Errorf("unsupported npy dtype in header %s", strings.TrimSpace(header))
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if descr[1] == ">" {
+		order = binary.BigEndian
+	}
+	fortran := npyFortran.FindStringSubmatch(header)
+	if fortran == nil {
+		return nil, fmt.Errorf("npy header %s has no fortran_order", strings.TrimSpace(header))
+	}
+	match := npyShape.FindStringSubmatch(header)
+	if match == nil {
+		return nil, fmt.Errorf("npy header %s has no shape", strings.TrimSpace(header))
+	}
+	size, _ := strconv.Atoi(descr[3])
+	var shape []int
+	n := 1
+	for _, d := range strings.Split(match[1], ",") {
+		if d = strings.TrimSpace(d); len(d) == 0 {
+			continue
+		}
+		v, e := strconv.Atoi(d)
+		if e != nil {
+			return nil, fmt.Errorf("wrong npy shape %s: %v", match[1], e)
+		}
+		if v > 0 && n > maxInt/size/v {
+			return nil, fmt.Errorf("npy array of shape %s is too large", match[1])
+		}
+		shape = append(shape, v)
+		n *= v
+	}
+
+	// data is read in chunks so memory grows with data actually read
+	k := n
+	if k > readChunk/size {
+		k = readChunk / size
+	}
+	data := make([]byte, k*size)
+	w := make([]float32, 0, k)
+	for len(w) < n {
+		if k > n-len(w) {
+			k = n - len(w)
+		}
+		if _, e := io.ReadFull(r, data[:k*size]); e != nil {
+			return nil, e
+		}
+		for i := 0; i < k; i++ {
+			if size == 4 {
+				w = append(w, math.Float32frombits(order.Uint32(data[4*i:])))
+			} else {
+				w = append(w, float32(math.Float64frombits(order.Uint64(data[8*i:]))))
+			}
+		}
+	}
+	t := &tensor{shape: shape, data: w}
+	m := t.matrix()
+	if fortran[1] == "True" && len(shape) > 1 {
+		if len(shape) > 2 {
+			return nil, fmt.Errorf("fortran order is not supported for %d dimensional arrays", len(shape))
+		}
+		// column major, swap back into row major
+		w = make([]float32, n)
+		for r := 0; r < m.Rows; r++ {
+			for c := 0; c < m.Columns; c++ {
+				w[r*m.Columns+c] = t.data[c*m.Rows+r]
+			}
+		}
+		m.W = w
+	}
+	return m, nil
+}
+
+// SaveNpy saves matrix to npy file
+func SaveNpy(name string, m *Matrix) error {
+	f, e := os.Create(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	if e = WriteNpy(f, m); e != nil {
+		return e
+	}
+	return f.Close()
+}
+
+// LoadNpy loads matrix from npy file
+func LoadNpy(name string) (*Matrix, error) {
+	f, e := os.Open(name)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	return ReadNpy(bufio.NewReader(f))
+}
+
+// WriteNpz writes model as uncompressed npz archive, one npy array per parameter
+func WriteNpz(w io.Writer, m map[string]*Matrix) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		f, e := zw.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: zip.Store})
+		if e != nil {
+			return e
+		}
+		if e = WriteNpy(f, m[name]); e != nil {
+			return fmt.Errorf("parameter %s: %v", name, e)
+		}
+	}
+	return zw.Close()
+}
+
+// ReadNpz reads npz archive (compressed or not), parameter names are array names without .npy suffix
+func ReadNpz(r io.ReaderAt, size int64) (map[string]*Matrix, error) {
+	zr, e := zip.NewReader(r, size)
+	if e != nil {
+		return nil, e
+	}
+	m := make(map[string]*Matrix, len(zr.File))
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".npy") {
+			continue
+		}
+		rc, e := f.Open()
+		if e != nil {
+			return nil, e
+		}
+		matrix, e := ReadNpy(bufio.NewReader(rc))
+		rc.Close()
+		if e != nil {
+			return nil, fmt.Errorf("array %s: %v", f.Name, e)
+		}
+		m[strings.TrimSuffix(f.Name, ".npy")] = matrix
+	}
+	return m, nil
+}
+
+// SaveNpz saves model to npz file
+func SaveNpz(name string, m map[string]*Matrix) error {
+	f, e := os.Create(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	if e = WriteNpz(f, m); e != nil {
+		return e
+	}
+	return f.Close()
+}
+
+// LoadNpz loads model from npz file
+func LoadNpz(name string) (map[string]*Matrix, error) {
+	f, e := os.Open(name)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	info, e := f.Stat()
+	if e != nil {
+		return nil, e
+	}
+	return ReadNpz(f, info.Size())
+}
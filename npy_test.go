@@ -0,0 +1,103 @@
+package gortex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestNpy(t *testing.T) {
+	dir := t.TempDir()
+	for _, m := range []*Matrix{RandMat(3, 5), RandMat(7, 1)} {
+		name := filepath.Join(dir, "m.npy")
+		if e := SaveNpy(name, m); e != nil {
+			t.Fatal(e)
+		}
+		loaded, e := LoadNpy(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if loaded.Rows != m.Rows || loaded.Columns != m.Columns {
+			t.Fatalf("%dx%d matrix loaded as %dx%d", m.Rows, m.Columns, loaded.Rows, loaded.Columns)
+		}
+		for i := range m.W {
+			if loaded.W[i] != m.W[i] {
+				t.Fatalf("weight %d mismatch", i)
+			}
+		}
+	}
+	model := map[string]*Matrix{"Encoder_Wf": RandMat(4, 2), "Encoder_Bf": RandMat(4, 1)}
+	name := filepath.Join(dir, "model.npz")
+	if e := SaveNpz(name, model); e != nil {
+		t.Fatal(e)
+	}
+	loaded, e := LoadNpz(name)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(loaded) != len(model) {
+		t.Fatalf("loaded %d arrays but %d saved", len(loaded), len(model))
+	}
+	for k, v := range model {
+		for i := range v.W {
+			if loaded[k].W[i] != v.W[i] {
+				t.Fatalf("parameter %s weight %d mismatch", k, i)
+			}
+		}
+	}
+}
+
+func TestNpyFloat64Fortran(t *testing.T) {
+	// 2x3 float64 array [[0 1 2] [3 4 5]] stored column major as numpy writes np.asfortranarray
+	header := "{'descr': '<f8', 'fortran_order': True, 'shape': (2, 3), }"
+	var buffer bytes.Buffer
+	buffer.WriteString(npyMagic)
+	buffer.Write([]byte{1, 0})
+	binary.Write(&buffer, binary.LittleEndian, uint16(len(header)+1))
+	buffer.WriteString(header + "\n")
+	for _, v := range []float64{0, 3, 1, 4, 2, 5} {
+		binary.Write(&buffer, binary.LittleEndian, math.Float64bits(v))
+	}
+	m, e := ReadNpy(&buffer)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if m.Rows != 2 || m.Columns != 3 {
+		t.Fatalf("array loaded as %dx%d", m.Rows, m.Columns)
+	}
+	for i, v := range m.W {
+		if v != float32(i) {
+			t.Fatalf("element %d is %f", i, v)
+		}
+	}
+}
+
+func TestNpyBrokenHeader(t *testing.T) {
+	npy := func(version byte, size int, header string) *bytes.Buffer {
+		var buffer bytes.Buffer
+		buffer.WriteString(npyMagic)
+		buffer.Write([]byte{version, 0})
+		if version == 1 {
+			binary.Write(&buffer, binary.LittleEndian, uint16(size))
+		} else {
+			binary.Write(&buffer, binary.LittleEndian, uint32(size))
+		}
+		buffer.WriteString(header)
+		buffer.Write(make([]byte, 16))
+		return &buffer
+	}
+	shape := func(s string) string { return "{'descr': '<f4', 'fortran_order': False, 'shape': " + s + ", }" }
+	for _, b := range []*bytes.Buffer{
+		npy(1, len(shape("(4611686018427387904, 4)")), shape("(4611686018427387904, 4)")),
+		npy(1, len(shape("(1000000000, 1000)")), shape("(1000000000, 1000)")),
+		npy(2, 1<<30, shape("(2,)")),
+	} {
+		if m, e := ReadNpy(b); e == nil {
+			t.Fatalf("broken header must be reported but %dx%d matrix is read", m.Rows, m.Columns)
+		} else {
+			t.Log(e)
+		}
+	}
+}
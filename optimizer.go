@@ -43,12 +43,20 @@ type OpOp struct {
 	Debug        bool
 	Beta         float32
 	Alpha        float32
+	Sparse       bool // update only touched columns of lookup tables (lazy semantics)
 }
 
 type OpRet struct {
 	L1Loss     float32
 	L2Loss     float32
 	NumClipped int
+	Skipped    bool // update was skipped by HealthMonitor
+}
+
+// Stepper updates model weights from accumulated gradients, implemented by Optimizer
+// and optimizer wrappers so they can be stacked
+type Stepper interface {
+	Step(model map[string]*Matrix) OpRet
 }
 
 type Optimizer struct {
@@ -151,132 +159,156 @@ func (o *Optimizer) Step(model map[string]*Matrix) OpRet {
 	// make method specific weight optimization
 	o.Iteration++
 	for name, m := range model {
-
-		if o.Clip > 0 {
-			ret.NumClipped += o.clip(m.DW)
+		if o.sparse(m) {
+			// lazy update, only columns which received gradient since last step
+			for _, col := range m.Touched() {
+				ret.NumClipped += o.update(name, m, col*m.Rows, (col+1)*m.Rows)
+			}
+			continue
 		}
-		if o.L1Decay > 0 {
-			for i := range m.W {
-				m.DW[i] += o.L1Decay * sign(m.W[i])
+		ret.NumClipped += o.update(name, m, 0, m.Numel())
+	}
+
+	// reset gradients
+	for _, m := range model {
+		if o.sparse(m) {
+			for _, col := range m.Touched() {
+				assembler.Sclean(m.DW[col*m.Rows : (col+1)*m.Rows])
 			}
+		} else {
+			assembler.Sclean(m.DW)
 		}
-		if o.L2Decay > 0 {
-			assembler.Saxpy(o.L2Decay, m.W, m.DW)
+		m.ResetTouched()
+	}
+
+	return ret
+}
+
+// sparse tells if lookup table is updated lazily, tables which also got dense gradient
+// e.g. from Mul when tied with softmax weights are updated fully
+func (o *Optimizer) sparse(m *Matrix) bool {
+	return o.Sparse && m.IsLookupTable() && !m.IsTouchedAll()
+}
+
+// update applies optimization method to the [lo:hi] region of m weights
+func (o *Optimizer) update(name string, m *Matrix, lo, hi int) (numClipped int) {
+	W := m.W[lo:hi]
+	DW := m.DW[lo:hi]
+	if o.Clip > 0 {
+		numClipped = o.clip(DW)
+	}
+	if o.L1Decay > 0 {
+		for i := range W {
+			DW[i] += o.L1Decay * sign(W[i])
 		}
+	}
+	if o.L2Decay > 0 {
+		assembler.Saxpy(o.L2Decay, W, DW)
+	}
 
-		if o.Debug && assembler.L2(m.DW) == 0 {
-			log.Printf("WARNING: %s W:%f DW:%f\n", name, assembler.L2(m.W), assembler.L2(m.DW))
+	if o.Debug && assembler.L2(DW) == 0 {
+		log.Printf("WARNING: %s W:%f DW:%f\n", name, assembler.L2(W), assembler.L2(DW))
+	}
+	switch o.Method {
+	case RMSPROP:
+		xsumi := o.getPreviousWeight(name, m)[lo:hi]
+		assembler.Saxplusbyvsetz(o.RmsDecayRate, xsumi, 1-o.RmsDecayRate, DW, DW, xsumi)
+		if o.Iteration > 10 {
+			assembler.Saxdivsqrteyplusz(-o.LearningRate, DW, o.Eps, xsumi, W)
 		}
-		switch o.Method {
-		case RMSPROP:
-			xsumi := o.getPreviousWeight(name, m)
-			assembler.Saxplusbyvsetz(o.RmsDecayRate, xsumi, 1-o.RmsDecayRate, m.DW, m.DW, xsumi)
-			if o.Iteration > 10 {
-				assembler.Saxdivsqrteyplusz(-o.LearningRate, m.DW, o.Eps, xsumi, m.W)
-			}
-		case ADAM:
-			gsumi := o.getPreviousGradient(name, m)
-			xsumi := o.getPreviousWeight(name, m)
-			assembler.Saxplusbysetz(o.Beta1, gsumi, 1-o.Beta1, m.DW, gsumi)        // update biased first moment estimate
-			assembler.Saxplusbyvsetz(o.Beta2, xsumi, 1-o.Beta2, m.DW, m.DW, xsumi) // update biased second moment estimate
-			beta1iteration := (1 - Pow(o.Beta1, o.Iteration))
-			beta2iteration := (1 - Pow(o.Beta2, o.Iteration))
-			biasCorr1 := make([]float32, m.Numel())
-			biasCorr2 := make([]float32, m.Numel())
-			assembler.Saxpy(beta1iteration, gsumi, biasCorr1) // correct bias first moment estimate
-			assembler.Saxpy(beta2iteration, xsumi, biasCorr2) // correct bias second moment estimate
-			if o.Iteration > 10 {
-				assembler.Saxdivsqrteyplusz(-o.LearningRate, biasCorr1, o.Eps, biasCorr2, m.W)
-			}
-		case ADAGRAD:
-			gsumi := o.getPreviousGradient(name, m)
-			assembler.Sxmuleyplusz(m.DW, m.DW, gsumi)
-			assembler.Saxdivsqrteyplusz(-o.LearningRate, m.DW, o.Eps, gsumi, m.W)
-		case WINDOWGRAD:
-			// this is adagrad but with a moving window weighted average
-			// so the gradient is not accumulated over the entire history of the run.
-			gsumi := o.getPreviousGradient(name, m)
-			assembler.Saxplusbyvsetz(o.Ro, gsumi, 1-o.Ro, m.DW, m.DW, gsumi)
-			assembler.Saxdivsqrteyplusz(-o.LearningRate, m.DW, o.Eps, gsumi, m.W)
-		case ADADELTA:
-			gsumi := o.getPreviousGradient(name, m)
-			xsumi := o.getPreviousWeight(name, m)
-			assembler.Saxplusbyvsetz(o.Ro, gsumi, 1-o.Ro, m.DW, m.DW, gsumi)
-			for i := range m.W {
-				dx := -assembler.Sqrt((xsumi[i] + o.Eps) / (gsumi[i] + o.Eps)) * m.DW[i]
-				xsumi[i] = o.Ro*xsumi[i] + (1-o.Ro)*dx*dx
-				m.W[i] += dx
-			}
-		case NETSTEROV:
-			dx := o.getPreviousGradient(name, m)
-			gsumi := make([]float32, m.Numel())
-			assembler.Saxplusbysetz(o.Momentum, dx, o.LearningRate, m.DW, gsumi)
-			assembler.Saxplusbyplusz(o.Momentum, dx, -(1 + o.Momentum), gsumi, m.W)
-			o.setPreviousGradient(name, gsumi)
-		case POWERBALL:
-			if o.Momentum > 0 {
-				dx := o.getPreviousGradient(name, m)
-				for i := range m.DW {
-					m.DW[i] = sign(m.DW[i]) * float32(math.Pow(float64(Abs(m.DW[i])), float64(o.Powerball)))
-				}
-				assembler.Saxplusbysetz(o.Momentum, dx, -o.LearningRate, m.DW, dx)
-				// apply corrected gradient
-				assembler.Sxpy(dx, m.W)
-			} else {
-				for i := range m.DW {
-					m.DW[i] = sign(m.DW[i]) * float32(math.Pow(float64(Abs(m.DW[i])), float64(o.Powerball)))
-				}
-				assembler.Saxpy(-o.LearningRate, m.DW, m.W)
-			}
-		case POWERSIGN:
-			gsumi := o.getPreviousGradient(name, m)
-			assembler.Saxplusbysetz(o.Beta, gsumi, 1-o.Beta, m.DW, gsumi)
-			update := o.getPreviousWeight(name,m)
-			for i := range m.W {
-				update[i] = float32(math.Exp(float64(sign(gsumi[i])*sign(m.DW[i])))) * m.DW[i]
-			}
-			assembler.Saxpy(-o.LearningRate, update, m.W)
-			/*
+	case ADAM:
+		gsumi := o.getPreviousGradient(name, m)[lo:hi]
+		xsumi := o.getPreviousWeight(name, m)[lo:hi]
+		assembler.Saxplusbysetz(o.Beta1, gsumi, 1-o.Beta1, DW, gsumi)      // update biased first moment estimate
+		assembler.Saxplusbyvsetz(o.Beta2, xsumi, 1-o.Beta2, DW, DW, xsumi) // update biased second moment estimate
+		beta1iteration := (1 - Pow(o.Beta1, o.Iteration))
+		beta2iteration := (1 - Pow(o.Beta2, o.Iteration))
+		biasCorr1 := make([]float32, len(W))
+		biasCorr2 := make([]float32, len(W))
+		assembler.Saxpy(beta1iteration, gsumi, biasCorr1) // correct bias first moment estimate
+		assembler.Saxpy(beta2iteration, xsumi, biasCorr2) // correct bias second moment estimate
+		if o.Iteration > 10 {
+			assembler.Saxdivsqrteyplusz(-o.LearningRate, biasCorr1, o.Eps, biasCorr2, W)
+		}
+	case ADAGRAD:
+		gsumi := o.getPreviousGradient(name, m)[lo:hi]
+		assembler.Sxmuleyplusz(DW, DW, gsumi)
+		assembler.Saxdivsqrteyplusz(-o.LearningRate, DW, o.Eps, gsumi, W)
+	case WINDOWGRAD:
+		// this is adagrad but with a moving window weighted average
+		// so the gradient is not accumulated over the entire history of the run.
+		gsumi := o.getPreviousGradient(name, m)[lo:hi]
+		assembler.Saxplusbyvsetz(o.Ro, gsumi, 1-o.Ro, DW, DW, gsumi)
+		assembler.Saxdivsqrteyplusz(-o.LearningRate, DW, o.Eps, gsumi, W)
+	case ADADELTA:
+		gsumi := o.getPreviousGradient(name, m)[lo:hi]
+		xsumi := o.getPreviousWeight(name, m)[lo:hi]
+		assembler.Saxplusbyvsetz(o.Ro, gsumi, 1-o.Ro, DW, DW, gsumi)
+		for i := range W {
+			dx := -assembler.Sqrt((xsumi[i]+o.Eps)/(gsumi[i]+o.Eps)) * DW[i]
+			xsumi[i] = o.Ro*xsumi[i] + (1-o.Ro)*dx*dx
+			W[i] += dx
+		}
+	case NETSTEROV:
+		dx := o.getPreviousGradient(name, m)[lo:hi]
+		gsumi := make([]float32, len(W))
+		assembler.Saxplusbysetz(o.Momentum, dx, o.LearningRate, DW, gsumi)
+		assembler.Saxplusbyplusz(o.Momentum, dx, -(1 + o.Momentum), gsumi, W)
+		copy(dx, gsumi)
+	case POWERBALL:
+		for i := range DW {
+			DW[i] = sign(DW[i]) * float32(math.Pow(float64(Abs(DW[i])), float64(o.Powerball)))
+		}
+		if o.Momentum > 0 {
+			dx := o.getPreviousGradient(name, m)[lo:hi]
+			assembler.Saxplusbysetz(o.Momentum, dx, -o.LearningRate, DW, dx)
+			// apply corrected gradient
+			assembler.Sxpy(dx, W)
+		} else {
+			assembler.Saxpy(-o.LearningRate, DW, W)
+		}
+	case POWERSIGN:
+		gsumi := o.getPreviousGradient(name, m)[lo:hi]
+		assembler.Saxplusbysetz(o.Beta, gsumi, 1-o.Beta, DW, gsumi)
+		update := o.getPreviousWeight(name, m)[lo:hi]
+		for i := range W {
+			update[i] = float32(math.Exp(float64(sign(gsumi[i])*sign(DW[i])))) * DW[i]
+		}
+		assembler.Saxpy(-o.LearningRate, update, W)
+		/*
 			t <- t + 1
 			m_t <- beta1 * m_{t-1} + (1 - beta1) * g
 			sign_decay <- sign_decay_fn(t)
 			update <- base ** (sign_decay * sign(g) * sign(m)) * g
 			variable <- variable - lr_t * update
-			*/
-		case ADDSIGN:
-			gsumi := o.getPreviousGradient(name, m)
-			assembler.Saxplusbysetz(o.Beta, gsumi, 1-o.Beta, m.DW, gsumi)
-			update := o.getPreviousWeight(name,m)
-			for i := range m.W {
-				update[i] = (o.Alpha + sign(gsumi[i])*sign(m.DW[i])) * m.DW[i]
-			}
-			assembler.Saxpy(-o.LearningRate, update, m.W)
-			/*
+		*/
+	case ADDSIGN:
+		gsumi := o.getPreviousGradient(name, m)[lo:hi]
+		assembler.Saxplusbysetz(o.Beta, gsumi, 1-o.Beta, DW, gsumi)
+		update := o.getPreviousWeight(name, m)[lo:hi]
+		for i := range W {
+			update[i] = (o.Alpha + sign(gsumi[i])*sign(DW[i])) * DW[i]
+		}
+		assembler.Saxpy(-o.LearningRate, update, W)
+		/*
 			t <- t + 1
-m_t <- beta1 * m_{t-1} + (1 - beta1) * g
-sign_decay <- sign_decay_fn(t)
-update <- (alpha + sign_decay * sign(g) *sign(m)) * g
-variable <- variable - lr_t * update
-			*/
-		case SGD:
-			if o.Momentum > 0 {
-				dx := o.getPreviousGradient(name, m)
-				assembler.Saxplusbysetz(o.Momentum, dx, -o.LearningRate, m.DW, dx)
-				// apply corrected gradient
-				assembler.Sxpy(dx, m.W)
-			} else {
-				// vanilla sgd no momentum
-				assembler.Saxpy(-o.LearningRate, m.DW, m.W)
-			}
-		default:
-			panic("Not implemented")
+			m_t <- beta1 * m_{t-1} + (1 - beta1) * g
+			sign_decay <- sign_decay_fn(t)
+			update <- (alpha + sign_decay * sign(g) *sign(m)) * g
+			variable <- variable - lr_t * update
+		*/
+	case SGD:
+		if o.Momentum > 0 {
+			dx := o.getPreviousGradient(name, m)[lo:hi]
+			assembler.Saxplusbysetz(o.Momentum, dx, -o.LearningRate, DW, dx)
+			// apply corrected gradient
+			assembler.Sxpy(dx, W)
+		} else {
+			// vanilla sgd no momentum
+			assembler.Saxpy(-o.LearningRate, DW, W)
 		}
+	default:
+		panic("Not implemented")
 	}
-
-	// reset gradients
-	for _, m := range model {
-		assembler.Sclean(m.DW)
-	}
-
-	return ret
+	return
 }
@@ -0,0 +1,35 @@
+package gortex
+
+import "github.com/vseledkin/gortex/assembler"
+
+// Gradient centralization wrapper https://arxiv.org/abs/2004.01461
+// removes mean from gradient of every output neuron weights (matrix row)
+// before inner optimizer step, vectors and lookup tables are left as is
+
+type GradientCentralization struct {
+	Inner Stepper
+}
+
+func NewGradientCentralization(inner Stepper) *GradientCentralization {
+	return &GradientCentralization{Inner: inner}
+}
+
+func (gc *GradientCentralization) Step(model map[string]*Matrix) OpRet {
+	for _, m := range model {
+		if m.Columns > 1 && !m.IsLookupTable() {
+			CentralizeGradient(m)
+		}
+	}
+	return gc.Inner.Step(model)
+}
+
+// CentralizeGradient subtracts row mean from every row of m gradient
+func CentralizeGradient(m *Matrix) {
+	for r := 0; r < m.Rows; r++ {
+		row := m.DW[r*m.Columns : (r+1)*m.Columns]
+		mean := assembler.Sum(row) / float32(m.Columns)
+		for i := range row {
+			row[i] -= mean
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package gortex
+
+import (
+	"fmt"
+
+	"github.com/vseledkin/gortex/assembler"
+)
+
+// Lookahead optimizer wrapper https://arxiv.org/abs/1907.08610
+// inner optimizer updates fast weights, every K steps slow weights move
+// Alpha of the way towards fast ones and fast weights are reset to slow
+
+type Lookahead struct {
+	Inner Stepper
+	K     int
+	Alpha float32
+	Slow  map[string][]float32
+	Steps int
+}
+
+func NewLookahead(inner Stepper, k int, alpha float32) *Lookahead {
+	if k < 1 {
+		panic(fmt.Errorf("lookahead k must be positive but %d given", k))
+	}
+	if alpha <= 0 || alpha > 1 {
+		panic(fmt.Errorf("lookahead alpha must be in (0;1] but %f given", alpha))
+	}
+	return &Lookahead{Inner: inner, K: k, Alpha: alpha, Slow: make(map[string][]float32)}
+}
+
+func (la *Lookahead) Step(model map[string]*Matrix) OpRet {
+	for name, m := range model {
+		if _, ok := la.Slow[name]; !ok {
+			// slow weights start from weights before first fast update
+			slow := make([]float32, m.Numel())
+			copy(slow, m.W)
+			la.Slow[name] = slow
+		}
+	}
+	ret := la.Inner.Step(model)
+	la.Steps++
+	if la.Steps%la.K == 0 {
+		for name, m := range model {
+			slow := la.Slow[name]
+			// slow += alpha * (fast - slow)
+			assembler.Saxplusbysetz(1-la.Alpha, slow, la.Alpha, m.W, slow)
+			copy(m.W, slow)
+		}
+	}
+	return ret
+}
@@ -9,3 +9,114 @@ func TestOptimizer(t *testing.T) {
 	}
 	println(op)
 }
+
+func TestSparseLookupUpdate(t *testing.T) {
+	for _, method := range []OpMethod{SGD, ADAM, RMSPROP, ADAGRAD, ADADELTA, WINDOWGRAD, NETSTEROV, POWERBALL, POWERSIGN, ADDSIGN} {
+		dense := RandMat(4, 100)
+		sparse := dense.CopyAs()
+		initial := dense.CopyAs()
+		denseOp := NewOptimizer(OpOp{Method: method, Momentum: DefaultMomentum})
+		sparseOp := NewOptimizer(OpOp{Method: method, Momentum: DefaultMomentum, Sparse: true})
+		for step := 0; step < 20; step++ {
+			for _, lt := range []*Matrix{dense, sparse} {
+				G := &Graph{NeedsBackprop: true}
+				G.MSE(G.Add(G.Lookup(lt, 3), G.Lookup(lt, 7)), Mat(4, 1))
+				G.Backward()
+			}
+			if cols := sparse.Touched(); len(cols) != 2 || cols[0] != 3 || cols[1] != 7 {
+				t.Fatalf("touched columns must be [3 7] but %v", cols)
+			}
+			denseOp.Step(map[string]*Matrix{"LookupTable": dense})
+			sparseOp.Step(map[string]*Matrix{"LookupTable": sparse})
+		}
+		for i := range sparse.W {
+			if sparse.DW[i] != 0 {
+				t.Fatalf("method %d gradient %d must be reset but %f", method, i, sparse.DW[i])
+			}
+			col := i / sparse.Rows
+			if col == 3 || col == 7 {
+				// touched every step, lazy update must match dense one
+				if Abs(sparse.W[i]-dense.W[i]) > 1e-5 {
+					t.Fatalf("method %d weight %d sparse %f dense %f", method, i, sparse.W[i], dense.W[i])
+				}
+			} else if sparse.W[i] != initial.W[i] {
+				t.Fatalf("method %d untouched weight %d must not change", method, i)
+			}
+		}
+	}
+}
+
+func TestStackedOptimizers(t *testing.T) {
+	W := RandXavierMat(10, 4)
+	b := RandXavierMat(10, 1)
+	x := RandMat(4, 1)
+	target := MatFromSlice([][]float32{{1}, {0}, {0}, {0}, {0}, {0}, {0}, {0}, {0}, {1}})
+	model := map[string]*Matrix{"W": W, "b": b}
+	var optimizer Stepper = NewOptimizer(OpOp{Method: SGD, LearningRate: 0.05, Momentum: DefaultMomentum})
+	optimizer = NewGradientCentralization(optimizer)
+	lookahead := NewLookahead(optimizer, 5, 0.5)
+	optimizer = lookahead
+	var mse float32
+	for i := 0; i < 500; i++ {
+		G := Graph{NeedsBackprop: true}
+		mse = G.MSE(G.Add(G.Mul(W, x), b), target)
+		G.Backward()
+		optimizer.Step(model)
+		if i%5 == 4 {
+			for k, m := range model {
+				for j := range m.W {
+					if m.W[j] != lookahead.Slow[k][j] {
+						t.Fatalf("fast weights must be reset to slow ones after sync")
+					}
+				}
+			}
+		}
+		if mse < 1e-4 {
+			break
+		}
+	}
+	if mse > 1e-4 {
+		t.Fatalf("model failed to optimize weights; mse=%f", mse)
+	}
+}
+
+func TestCentralizeGradient(t *testing.T) {
+	m := Mat(2, 3)
+	copy(m.DW, []float32{1, 2, 3, -1, 0, 4})
+	CentralizeGradient(m)
+	for r := 0; r < m.Rows; r++ {
+		var sum float32
+		for c := 0; c < m.Columns; c++ {
+			sum += m.GetGradient(r, c)
+		}
+		if Abs(sum) > 1e-6 {
+			t.Fatalf("row %d gradient mean must be zero but sum is %f", r, sum)
+		}
+	}
+}
+
+func TestSparseTiedLookupUpdate(t *testing.T) {
+	// embeddings used as softmax weights get gradient of every column
+	tied := RandMat(4, 10)
+	initial := tied.CopyAs()
+	op := NewOptimizer(OpOp{Method: SGD, LearningRate: 0.1, Sparse: true})
+	G := &Graph{NeedsBackprop: true}
+	h := G.Lookup(tied, 3)
+	G.MSE(G.Add(G.Mul(tied, Mat(10, 1).OnesAs()), h), Mat(4, 1))
+	G.Backward()
+	if !tied.IsTouchedAll() {
+		t.Fatal("matrix multiplied must be marked for dense update")
+	}
+	op.Step(map[string]*Matrix{"Tied": tied})
+	for i := range tied.DW {
+		if tied.DW[i] != 0 {
+			t.Fatalf("gradient %d must be reset but %f", i, tied.DW[i])
+		}
+	}
+	if tied.IsTouchedAll() || !tied.IsLookupTable() {
+		t.Fatal("dense use must be reset after step, lookup table mark kept")
+	}
+	if tied.W[4*5] == initial.W[4*5] {
+		t.Fatal("column not looked up must be updated by its dense gradient")
+	}
+}
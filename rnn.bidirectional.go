@@ -0,0 +1,118 @@
+package gortex
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MergeMode is how BiRNN combines outputs of both directions
+type MergeMode int
+
+const (
+	MergeConcat MergeMode = iota // output size is sum of both output sizes
+	MergeSum
+	MergeMean
+)
+
+// MaskedSequenceLayer is sequence layer able to skip padding steps, outputs of padding steps are nil
+type MaskedSequenceLayer interface {
+	SequenceLayer
+	ForwardMasked(g *Graph, x []*Matrix, mask []bool) []*Matrix
+}
+
+// BiRNN runs one cell over sequence from start and another from end and merges their outputs step by step
+type BiRNN struct {
+	Forward  RecurrentCell
+	Backward RecurrentCell
+	Merge    MergeMode
+}
+
+// MakeBiRNN makes bidirectional wrapper of any cells adapted by AsRecurrentCell
+func MakeBiRNN(forward, backward interface{}, merge MergeMode) *BiRNN {
+	rnn := &BiRNN{Merge: merge}
+	var e error
+	if rnn.Forward, e = AsRecurrentCell(forward); e != nil {
+		panic(e)
+	}
+	if rnn.Backward, e = AsRecurrentCell(backward); e != nil {
+		panic(e)
+	}
+	return rnn
+}
+
+func (rnn *BiRNN) GetParameters(namespace string) map[string]*Matrix {
+	p := rnn.Forward.GetParameters(namespace + "_forward")
+	for k, v := range rnn.Backward.GetParameters(namespace + "_backward") {
+		p[k] = v
+	}
+	return p
+}
+
+func (rnn *BiRNN) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(rnn.GetParameters(namespace), parameters)
+}
+
+func (rnn *BiRNN) merge(g *Graph, forward, backward *Matrix) *Matrix {
+	switch rnn.Merge {
+	case MergeConcat:
+		return g.Concat(forward, backward)
+	case MergeSum:
+		return g.Add(forward, backward)
+	case MergeMean:
+		return g.MulConstant(0.5, g.Add(forward, backward))
+	}
+	panic(fmt.Errorf("unknown merge mode %d", rnn.Merge))
+}
+
+// Run returns merged outputs and last valid states of both directions,
+// backward state is the one after first valid step
+func (rnn *BiRNN) Run(g *Graph, x []*Matrix, mask []bool) (y []*Matrix, forward, backward *State) {
+	f := Unroll(g, rnn.Forward, nil, x, &UnrollOp{Mask: mask})
+	b := Unroll(g, rnn.Backward, nil, x, &UnrollOp{Mask: mask, Reverse: true})
+	y = make([]*Matrix, len(x))
+	for t := range y {
+		if mask == nil || mask[t] {
+			y[t] = rnn.merge(g, f.Outputs[t], b.Outputs[t])
+		}
+	}
+	return y, f.Last, b.Last
+}
+
+func (rnn *BiRNN) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	y, _, _ := rnn.Run(g, x, nil)
+	return y
+}
+
+func (rnn *BiRNN) ForwardMasked(g *Graph, x []*Matrix, mask []bool) []*Matrix {
+	y, _, _ := rnn.Run(g, x, mask)
+	return y
+}
+
+// BiRNNConfig makes BiRNN from registered cell architectures
+type BiRNNConfig struct {
+	Namespace string // parameters namespace, BiRNN if empty
+	Forward   LayerConfig
+	Backward  LayerConfig
+	Merge     MergeMode
+}
+
+func init() {
+	RegisterArchitecture("BiRNN", func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(BiRNNConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("BiRNN config: %v", e)
+		}
+		rnn := &BiRNN{Merge: c.Merge}
+		var e error
+		if rnn.Forward, e = buildCell(c.Forward); e != nil {
+			return nil, nil, fmt.Errorf("BiRNN forward %v", e)
+		}
+		if rnn.Backward, e = buildCell(c.Backward); e != nil {
+			return nil, nil, fmt.Errorf("BiRNN backward %v", e)
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = "BiRNN"
+		}
+		return rnn, rnn.GetParameters(c.Namespace), nil
+	})
+}
@@ -1,7 +1,5 @@
 package gortex
 
-import "fmt"
-
 // DeltaRNN cell https://arxiv.org/pdf/1703.08864.pdf
 type DeltaRNN struct {
 	Wr   *Matrix
@@ -17,18 +15,19 @@ type DeltaRNN struct {
 }
 
 // MakeDeltaRNN create new cell
-func MakeDeltaRNN(x_size, h_size, out_size int) *DeltaRNN {
+func MakeDeltaRNN(x_size, h_size, out_size int, options ...InitOption) *DeltaRNN {
 	net := new(DeltaRNN)
-	net.Wr = RandXavierMat(h_size, x_size)
-	net.Ur = RandXavierMat(h_size, h_size)
-	net.Wx = RandXavierMat(h_size, x_size)
-	net.Wh = RandXavierMat(h_size, h_size)
-	net.Wo = RandXavierMat(out_size, h_size)
-	net.Br = RandXavierMat(h_size, 1)
-	net.Bias = RandXavierMat(h_size, 1)
-	net.A = RandXavierMat(h_size, 1)
-	net.B = RandXavierMat(h_size, 1)
-	net.C = RandXavierMat(h_size, 1)
+	in := xavier(options)
+	net.Wr = in.weights(h_size, x_size)
+	net.Ur = in.recurrent(h_size, h_size)
+	net.Wx = in.weights(h_size, x_size)
+	net.Wh = in.recurrent(h_size, h_size)
+	net.Wo = in.weights(out_size, h_size)
+	net.Br = in.biases(h_size)
+	net.Bias = in.biases(h_size)
+	net.A = in.biases(h_size)
+	net.B = in.biases(h_size)
+	net.C = in.biases(h_size)
 	return net
 }
 
@@ -47,16 +46,7 @@ func (rnn *DeltaRNN) GetParameters(namespace string) map[string]*Matrix {
 }
 
 func (rnn *DeltaRNN) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			v.W = m.W
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *DeltaRNN) Step(g *Graph, x, h_prev *Matrix) (h, y *Matrix) {
@@ -0,0 +1,147 @@
+package gortex
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func closeMatrices(t *testing.T, a, b []float32, message string) {
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > 1e-4*math.Max(1, math.Abs(float64(a[i]))) {
+			t.Fatalf("%s: element %d differs %f != %f", message, i, a[i], b[i])
+		}
+	}
+}
+
+func TestMulMatrices(t *testing.T) {
+	m1, m2 := RandMat(5, 7), RandMat(7, 3)
+	G := &Graph{NeedsBackprop: true}
+	out := G.Mul(m1, m2)
+	copy(out.DW, RandMat(5, 3).W)
+	G.Backward()
+	expectedDW1, expectedDW2 := make([]float32, len(m1.W)), make([]float32, len(m2.W))
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 3; j++ {
+			var dot float32
+			for k := 0; k < 7; k++ {
+				dot += m1.W[7*i+k] * m2.W[3*k+j]
+				expectedDW1[7*i+k] += m2.W[3*k+j] * out.DW[3*i+j]
+				expectedDW2[3*k+j] += m1.W[7*i+k] * out.DW[3*i+j]
+			}
+			if math.Abs(float64(dot-out.W[3*i+j])) > 1e-5 {
+				t.Fatalf("product element %d,%d is %f instead of %f", i, j, out.W[3*i+j], dot)
+			}
+		}
+	}
+	closeMatrices(t, m1.DW, expectedDW1, "m1 gradient")
+	closeMatrices(t, m2.DW, expectedDW2, "m2 gradient")
+
+	packed := G.PackColumnVectors([]*Matrix{RandMat(4, 1), RandMat(4, 1)})
+	columns := G.UnpackColumnVectors(packed)
+	if len(columns) != 2 || columns[1].W[3] != packed.W[7] {
+		t.Fatal("unpacked columns differ from packed ones")
+	}
+}
+
+// BenchmarkMulMatrices is projection of sequence of 32 steps as SRU and QRNN do
+func BenchmarkMulMatrices(b *testing.B) {
+	m1, m2 := RandMat(256, 256), RandMat(256, 32)
+	for i := 0; i < b.N; i++ {
+		G := &Graph{NeedsBackprop: true}
+		copy(G.Mul(m1, m2).DW, m2.W[:256*32])
+		G.Backward()
+	}
+}
+
+func TestSequenceProjections(t *testing.T) {
+	x := []*Matrix{RandMat(6, 1), RandMat(6, 1), RandMat(6, 1), RandMat(6, 1)}
+	for _, c := range []interface{}{MakeSRU(6, 8, 3), MakeSRU(6, 6, 3), MakeQRNN(6, 8, 3)} {
+		cell, _ := AsRecurrentCell(c)
+		layer, _ := AsSequenceLayer(c)
+		// step by step
+		G := &Graph{NeedsBackprop: true}
+		steps := Unroll(G, cell, nil, x, nil)
+		for _, y := range steps.Outputs {
+			for i := range y.DW {
+				y.DW[i] = 1
+			}
+		}
+		G.Backward()
+		expected := make(map[string][]float32)
+		for k, m := range cell.GetParameters("cell") {
+			expected[k] = append([]float32(nil), m.DW...)
+			for i := range m.DW {
+				m.DW[i] = 0
+			}
+		}
+		// whole sequence at once
+		G = &Graph{NeedsBackprop: true}
+		y := layer.ForwardSequence(G, x)
+		for t := range y {
+			for i := range y[t].DW {
+				y[t].DW[i] = 1
+			}
+		}
+		G.Backward()
+		for i := range y {
+			closeMatrices(t, y[i].W, steps.Outputs[i].W, "sequence output")
+		}
+		for k, m := range cell.GetParameters("cell") {
+			closeMatrices(t, m.DW, expected[k], k+" gradient")
+		}
+		if y := layer.ForwardSequence(&Graph{}, nil); len(y) != 0 {
+			t.Fatal("empty sequence must have empty output")
+		}
+	}
+
+	mgu := MakeMGU(6, 8, 3)
+	cell, _ := AsRecurrentCell(mgu)
+	G := &Graph{NeedsBackprop: true}
+	ret := Unroll(G, cell, nil, x, nil)
+	if ret.Output.Rows != 3 || ret.Last.H.Rows != 8 {
+		t.Fatal("wrong MGU output")
+	}
+	G.Backward()
+	if b, e := NewBundle("SRU", &CellConfig{Input: 6, Hidden: 8, Output: 3}); e != nil || len(b.Parameters) != 7 {
+		t.Fatalf("SRU is not registered properly: %v", e)
+	}
+}
+
+// character language model step of one sequence, forward and backward
+func benchmarkCharLM(b *testing.B, cell interface{}) {
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog. ", 10)
+	dic := NewDictionary()
+	for _, char := range (CharSplitter{}).Split(text) {
+		dic.Add(char)
+	}
+	ids := make([]uint, 0, 64)
+	for _, char := range (CharSplitter{}).Split(text)[:64] {
+		ids = append(ids, dic.IDByToken(char))
+	}
+	embeddings := RandMat(64, dic.Len())
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		G := &Graph{NeedsBackprop: true}
+		x := make([]*Matrix, len(ids)-1)
+		for i := range x {
+			x[i] = G.Lookup(embeddings, int(ids[i]))
+		}
+		var y []*Matrix
+		if layer, ok := cell.(SequenceLayer); ok {
+			y = layer.ForwardSequence(G, x)
+		} else {
+			c, _ := AsRecurrentCell(cell)
+			y = Unroll(G, c, nil, x, nil).Outputs
+		}
+		for i := range y {
+			G.Crossentropy(y[i], ids[i+1])
+		}
+		G.Backward()
+	}
+}
+
+func BenchmarkCharLMGRU(b *testing.B)  { benchmarkCharLM(b, MakeGRU(64, 128, 40)) }
+func BenchmarkCharLMMGU(b *testing.B)  { benchmarkCharLM(b, MakeMGU(64, 128, 40)) }
+func BenchmarkCharLMSRU(b *testing.B)  { benchmarkCharLM(b, MakeSRU(64, 128, 40)) }
+func BenchmarkCharLMQRNN(b *testing.B) { benchmarkCharLM(b, MakeQRNN(64, 128, 40)) }
@@ -1,6 +1,5 @@
 package gortex
 
-import "fmt"
 import "github.com/vseledkin/gortex/assembler"
 
 // Gated recurrent unit
@@ -19,6 +18,8 @@ type GRU struct {
 	Bh *Matrix
 
 	Who *Matrix
+
+	Dropout *RecurrentDropout // optional regularization of recurrent connections
 }
 
 func (gru *GRU) ForgetGateTrick(v float32) {
@@ -27,21 +28,22 @@ func (gru *GRU) ForgetGateTrick(v float32) {
 	}
 }
 
-func MakeGRU(x_size, h_size, out_size int) *GRU {
+func MakeGRU(x_size, h_size, out_size int, options ...InitOption) *GRU {
 	rnn := new(GRU)
-	rnn.Wz = RandXavierMat(h_size, x_size)
-	rnn.Uz = RandXavierMat(h_size, h_size)
-	rnn.Bz = RandXavierMat(h_size, 1)
+	in := xavier(options)
+	rnn.Wz = in.weights(h_size, x_size)
+	rnn.Uz = in.recurrent(h_size, h_size)
+	rnn.Bz = in.biases(h_size)
 
-	rnn.Wr = RandXavierMat(h_size, x_size)
-	rnn.Ur = RandXavierMat(h_size, h_size)
-	rnn.Br = RandXavierMat(h_size, 1)
+	rnn.Wr = in.weights(h_size, x_size)
+	rnn.Ur = in.recurrent(h_size, h_size)
+	rnn.Br = in.biases(h_size)
 
-	rnn.Wh = RandXavierMat(h_size, x_size)
-	rnn.Uh = RandXavierMat(h_size, h_size)
-	rnn.Bh = RandXavierMat(h_size, 1)
+	rnn.Wh = in.weights(h_size, x_size)
+	rnn.Uh = in.recurrent(h_size, h_size)
+	rnn.Bh = in.biases(h_size)
 
-	rnn.Who = RandXavierMat(out_size, h_size)
+	rnn.Who = in.weights(out_size, h_size)
 	return rnn
 }
 
@@ -64,26 +66,20 @@ func (rnn *GRU) GetParameters(namespace string) map[string]*Matrix {
 }
 
 func (rnn *GRU) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *GRU) Step(g *Graph, x, h_prev *Matrix) (h, y *Matrix) {
 	// make GRU computation graph at one time-step
-	zt := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wz, x), g.Mul(rnn.Uz, h_prev)), rnn.Bz))
-	rt := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wr, x), g.Mul(rnn.Ur, h_prev)), rnn.Br))
+	rd := rnn.Dropout
+	x, hd := rd.inputs(g, rnn, x, h_prev)
+	zt := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wz, x), g.Mul(rd.recurrent(g, rnn.Uz), hd)), rnn.Bz))
+	rt := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wr, x), g.Mul(rd.recurrent(g, rnn.Ur), hd)), rnn.Br))
 
-	ht := g.Tanh(g.Add(g.Add(g.Mul(rnn.Wh, x), g.Mul(rnn.Uh, g.EMul(rt, h_prev))), rnn.Bh))
+	ht := g.Tanh(g.Add(g.Add(g.Mul(rnn.Wh, x), g.Mul(rd.recurrent(g, rnn.Uh), g.EMul(rt, hd))), rnn.Bh))
 	//h = g.InstanceNormalization(g.Add(g.EMul(zt, h_prev), g.EMul(g.Sub(zt.OnesAs(), zt), ht)))
 	h = g.Add(g.EMul(zt, h_prev), g.EMul(g.Sub(zt.OnesAs(), zt), ht))
+	h, _ = rd.zoneout(g, h_prev, h, nil, nil)
 
 	y = g.Mul(rnn.Who, g.Tanh(h))
 	return
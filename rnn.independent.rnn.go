@@ -19,25 +19,26 @@ type IndRNN struct {
 	Who *Matrix
 }
 
-func MakeIndRNN(layers, x_size, length, h_size, out_size int) *IndRNN {
+func MakeIndRNN(layers, x_size, length, h_size, out_size int, options ...InitOption) *IndRNN {
 	rnn := new(IndRNN)
+	in := NewInitialization(lsuvMat, XavierNormal{}, Constant{}, options...)
 	rnn.U = make([][]*Matrix, layers) // layers/length
 	rnn.W = make([]*Matrix, layers)   // layers/length
 	rnn.B = make([]*Matrix, layers)   // layers/length
 	for l := range rnn.U {
 		if l == 0 {
-			rnn.W[l] = LSUVMat(h_size, x_size)
+			rnn.W[l] = in.weights(h_size, x_size)
 		} else {
-			rnn.W[l] = LSUVMat(h_size, h_size)
+			rnn.W[l] = in.weights(h_size, h_size)
 		}
 		rnn.U[l] = make([]*Matrix, length)
 		for i := range rnn.U[l] {
-			rnn.U[l][i] = RandXavierMat(h_size, 1)
+			rnn.U[l][i] = in.recurrent(h_size, 1)
 		}
-		rnn.B[l] = Mat(h_size, 1)
+		rnn.B[l] = in.biases(h_size)
 	}
 
-	rnn.Who = LSUVMat(out_size, h_size)
+	rnn.Who = in.weights(out_size, h_size)
 	return rnn
 }
 
@@ -57,16 +58,7 @@ func (rnn *IndRNN) GetParameters(namespace string) map[string]*Matrix {
 }
 
 func (rnn *IndRNN) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *IndRNN) Step(g *Graph, i int, x *Matrix, h_prev []*Matrix) (h []*Matrix, y *Matrix) {
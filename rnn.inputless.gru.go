@@ -1,6 +1,5 @@
 package gortex
 
-import "fmt"
 import "github.com/vseledkin/gortex/assembler"
 
 // Gated recurrent unit
@@ -24,19 +23,20 @@ func (gru *InputlessGRU) ForgetGateTrick(v float32) {
 	}
 }
 
-func MakeInputlessGRU(h_size, out_size int) *InputlessGRU {
+func MakeInputlessGRU(h_size, out_size int, options ...InitOption) *InputlessGRU {
 	rnn := new(InputlessGRU)
+	in := xavier(options)
 
-	rnn.Uz = RandXavierMat(h_size, h_size)
-	rnn.Bz = RandXavierMat(h_size, 1)
+	rnn.Uz = in.recurrent(h_size, h_size)
+	rnn.Bz = in.biases(h_size)
 
-	rnn.Ur = RandXavierMat(h_size, h_size)
-	rnn.Br = RandXavierMat(h_size, 1)
+	rnn.Ur = in.recurrent(h_size, h_size)
+	rnn.Br = in.biases(h_size)
 
-	rnn.Uh = RandXavierMat(h_size, h_size)
-	rnn.Bh = RandXavierMat(h_size, 1)
+	rnn.Uh = in.recurrent(h_size, h_size)
+	rnn.Bh = in.biases(h_size)
 
-	rnn.Who = RandXavierMat(out_size, h_size)
+	rnn.Who = in.weights(out_size, h_size)
 	return rnn
 }
 
@@ -57,16 +57,7 @@ func (rnn *InputlessGRU) GetParameters(namespace string) map[string]*Matrix {
 }
 
 func (rnn *InputlessGRU) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *InputlessGRU) Step(g *Graph, h_prev *Matrix) (h, y *Matrix) {
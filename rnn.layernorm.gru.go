@@ -0,0 +1,93 @@
+package gortex
+
+import (
+	"github.com/vseledkin/gortex/assembler"
+)
+
+// Layer normalized gated recurrent unit
+// https://arxiv.org/abs/1607.06450
+// gate pre-activations are normalized, biases B* are shifts of normalization
+
+type LayerNormGRU struct {
+	Wz *Matrix
+	Uz *Matrix
+	Gz *Matrix
+	Bz *Matrix
+
+	Wr *Matrix
+	Ur *Matrix
+	Gr *Matrix
+	Br *Matrix
+
+	Wh *Matrix
+	Uh *Matrix
+	Gh *Matrix
+	Bh *Matrix
+
+	Who *Matrix
+}
+
+func (gru *LayerNormGRU) ForgetGateTrick(v float32) {
+	if gru.Bz != nil {
+		assembler.Sset(v, gru.Bz.W)
+	}
+}
+
+func MakeLayerNormGRU(x_size, h_size, out_size int, options ...InitOption) *LayerNormGRU {
+	rnn := new(LayerNormGRU)
+	in := NewInitialization(XavierNormal{}, XavierNormal{}, Constant{}, options...)
+	rnn.Wz = in.weights(h_size, x_size)
+	rnn.Uz = in.recurrent(h_size, h_size)
+	rnn.Gz = Mat(h_size, 1).OnesAs()
+	rnn.Bz = in.biases(h_size)
+
+	rnn.Wr = in.weights(h_size, x_size)
+	rnn.Ur = in.recurrent(h_size, h_size)
+	rnn.Gr = Mat(h_size, 1).OnesAs()
+	rnn.Br = in.biases(h_size)
+
+	rnn.Wh = in.weights(h_size, x_size)
+	rnn.Uh = in.recurrent(h_size, h_size)
+	rnn.Gh = Mat(h_size, 1).OnesAs()
+	rnn.Bh = in.biases(h_size)
+
+	rnn.Who = in.weights(out_size, h_size)
+	return rnn
+}
+
+func (rnn *LayerNormGRU) GetParameters(namespace string) map[string]*Matrix {
+	return map[string]*Matrix{
+		namespace + "_Wz": rnn.Wz,
+		namespace + "_Uz": rnn.Uz,
+		namespace + "_Gz": rnn.Gz,
+		namespace + "_Bz": rnn.Bz,
+
+		namespace + "_Wr": rnn.Wr,
+		namespace + "_Ur": rnn.Ur,
+		namespace + "_Gr": rnn.Gr,
+		namespace + "_Br": rnn.Br,
+
+		namespace + "_Wh": rnn.Wh,
+		namespace + "_Uh": rnn.Uh,
+		namespace + "_Gh": rnn.Gh,
+		namespace + "_Bh": rnn.Bh,
+
+		namespace + "_Who": rnn.Who,
+	}
+}
+
+func (rnn *LayerNormGRU) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(rnn.GetParameters(namespace), parameters)
+}
+
+func (rnn *LayerNormGRU) Step(g *Graph, x, h_prev *Matrix) (h, y *Matrix) {
+	// make layer normalized GRU computation graph at one time-step
+	zt := g.Sigmoid(g.LayerNorm(g.Add(g.Mul(rnn.Wz, x), g.Mul(rnn.Uz, h_prev)), rnn.Gz, rnn.Bz))
+	rt := g.Sigmoid(g.LayerNorm(g.Add(g.Mul(rnn.Wr, x), g.Mul(rnn.Ur, h_prev)), rnn.Gr, rnn.Br))
+
+	ht := g.Tanh(g.LayerNorm(g.Add(g.Mul(rnn.Wh, x), g.Mul(rnn.Uh, g.EMul(rt, h_prev))), rnn.Gh, rnn.Bh))
+	h = g.Add(g.EMul(zt, h_prev), g.EMul(g.Sub(zt.OnesAs(), zt), ht))
+
+	y = g.Mul(rnn.Who, g.Tanh(h))
+	return
+}
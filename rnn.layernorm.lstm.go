@@ -0,0 +1,119 @@
+package gortex
+
+import (
+	"github.com/vseledkin/gortex/assembler"
+)
+
+// Layer normalized Long Short Term Memory cell
+// https://arxiv.org/abs/1607.06450
+// gate pre-activations and memory are normalized, biases B* are shifts of normalization
+
+type LayerNormLSTM struct {
+	Wf *Matrix
+	Uf *Matrix
+	Gf *Matrix
+	Bf *Matrix
+
+	Wi *Matrix
+	Ui *Matrix
+	Gi *Matrix
+	Bi *Matrix
+
+	Wo *Matrix
+	Uo *Matrix
+	Go *Matrix
+	Bo *Matrix
+
+	Wc *Matrix
+	Uc *Matrix
+	Gc *Matrix
+	Bc *Matrix
+
+	// normalization of memory before output
+	Gm *Matrix
+	Bm *Matrix
+
+	Who *Matrix
+}
+
+func (lstm *LayerNormLSTM) ForgetGateTrick(v float32) {
+	if lstm.Bf != nil {
+		assembler.Sset(v, lstm.Bf.W)
+	}
+}
+
+func MakeLayerNormLSTM(x_size, h_size, out_size int, options ...InitOption) *LayerNormLSTM {
+	rnn := new(LayerNormLSTM)
+	in := NewInitialization(XavierNormal{}, XavierNormal{}, Constant{}, options...)
+	rnn.Wf = in.weights(h_size, x_size)
+	rnn.Uf = in.recurrent(h_size, h_size)
+	rnn.Gf = Mat(h_size, 1).OnesAs()
+	rnn.Bf = in.biases(h_size) // forget gate bias initialization trick will be applied here
+
+	rnn.Wi = in.weights(h_size, x_size)
+	rnn.Ui = in.recurrent(h_size, h_size)
+	rnn.Gi = Mat(h_size, 1).OnesAs()
+	rnn.Bi = in.biases(h_size)
+
+	rnn.Wo = in.weights(h_size, x_size)
+	rnn.Uo = in.recurrent(h_size, h_size)
+	rnn.Go = Mat(h_size, 1).OnesAs()
+	rnn.Bo = in.biases(h_size)
+
+	rnn.Wc = in.weights(h_size, x_size)
+	rnn.Uc = in.recurrent(h_size, h_size)
+	rnn.Gc = Mat(h_size, 1).OnesAs()
+	rnn.Bc = in.biases(h_size)
+
+	rnn.Gm = Mat(h_size, 1).OnesAs()
+	rnn.Bm = in.biases(h_size)
+
+	rnn.Who = in.weights(out_size, h_size)
+	return rnn
+}
+
+func (rnn *LayerNormLSTM) GetParameters(namespace string) map[string]*Matrix {
+	return map[string]*Matrix{
+		namespace + "_Wf": rnn.Wf,
+		namespace + "_Uf": rnn.Uf,
+		namespace + "_Gf": rnn.Gf,
+		namespace + "_Bf": rnn.Bf,
+
+		namespace + "_Wi": rnn.Wi,
+		namespace + "_Ui": rnn.Ui,
+		namespace + "_Gi": rnn.Gi,
+		namespace + "_Bi": rnn.Bi,
+
+		namespace + "_Wo": rnn.Wo,
+		namespace + "_Uo": rnn.Uo,
+		namespace + "_Go": rnn.Go,
+		namespace + "_Bo": rnn.Bo,
+
+		namespace + "_Wc": rnn.Wc,
+		namespace + "_Uc": rnn.Uc,
+		namespace + "_Gc": rnn.Gc,
+		namespace + "_Bc": rnn.Bc,
+
+		namespace + "_Gm": rnn.Gm,
+		namespace + "_Bm": rnn.Bm,
+
+		namespace + "_Who": rnn.Who,
+	}
+}
+
+func (rnn *LayerNormLSTM) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(rnn.GetParameters(namespace), parameters)
+}
+
+func (rnn *LayerNormLSTM) Step(g *Graph, x, h_prev, c_prev *Matrix) (h, c, y *Matrix) {
+	// make layer normalized LSTM computation graph at one time-step
+	f := g.Sigmoid(g.LayerNorm(g.Add(g.Mul(rnn.Wf, x), g.Mul(rnn.Uf, h_prev)), rnn.Gf, rnn.Bf))
+	i := g.Sigmoid(g.LayerNorm(g.Add(g.Mul(rnn.Wi, x), g.Mul(rnn.Ui, h_prev)), rnn.Gi, rnn.Bi))
+	o := g.Sigmoid(g.LayerNorm(g.Add(g.Mul(rnn.Wo, x), g.Mul(rnn.Uo, h_prev)), rnn.Go, rnn.Bo))
+	c = g.Tanh(g.LayerNorm(g.Add(g.Mul(rnn.Wc, x), g.Mul(rnn.Uc, h_prev)), rnn.Gc, rnn.Bc))
+	c = g.Add(g.EMul(f, c_prev), g.EMul(i, c))
+	h = g.EMul(o, g.Tanh(g.LayerNorm(c, rnn.Gm, rnn.Bm)))
+
+	y = g.Mul(rnn.Who, h)
+	return
+}
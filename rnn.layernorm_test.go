@@ -0,0 +1,110 @@
+package gortex
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLayerNorm(t *testing.T) {
+	x := RandMatMD(7, 1, 3, 5)
+	gain, bias := RandMat(7, 1), RandMat(7, 1)
+	weights := RandMat(7, 1) // loss is weighted sum of outputs
+	loss := func() float32 {
+		out := (&Graph{}).LayerNorm(x, gain, bias)
+		var l float32
+		for i := range out.W {
+			l += out.W[i] * weights.W[i]
+		}
+		return l
+	}
+	G := &Graph{NeedsBackprop: true}
+	out := G.LayerNorm(x, gain, bias)
+	copy(out.DW, weights.W)
+	G.Backward()
+
+	// compare analytic gradients with finite differences
+	for _, m := range []*Matrix{x, gain, bias} {
+		for i := range m.W {
+			v := m.W[i]
+			m.W[i] = v + 1e-2
+			plus := loss()
+			m.W[i] = v - 1e-2
+			minus := loss()
+			m.W[i] = v
+			numeric := (plus - minus) / 2e-2
+			if math.Abs(float64(numeric-m.DW[i])) > 1e-2*math.Max(1, math.Abs(float64(numeric))) {
+				t.Fatalf("gradient %d is %f but numeric one is %f", i, m.DW[i], numeric)
+			}
+		}
+	}
+
+	normalized := (&Graph{}).LayerNorm(x, Mat(7, 1).OnesAs(), Mat(7, 1))
+	mean, variance := Moments(normalized)
+	if math.Abs(float64(mean)) > 1e-5 || math.Abs(float64(variance-1)) > 1e-3 {
+		t.Fatalf("normalized vector has mean %f and variance %f", mean, variance)
+	}
+}
+
+func TestNormalizedAndPeepholeCells(t *testing.T) {
+	cells := map[string]interface{}{
+		"LayerNormLSTM": MakeLayerNormLSTM(5, 8, 3),
+		"LayerNormGRU":  MakeLayerNormGRU(5, 8, 3),
+		"PeepholeLSTM":  MakePeepholeLSTM(5, 8, 3),
+	}
+	for name, c := range cells {
+		c.(interface{ ForgetGateTrick(float32) }).ForgetGateTrick(2)
+		p := c.(Parameterized).GetParameters(name)
+		gate := p[name+"_Bf"]
+		if gate == nil {
+			gate = p[name+"_Bz"]
+		}
+		for _, v := range gate.W {
+			if v != 2 {
+				t.Fatalf("%s forget gate trick is not applied", name)
+			}
+		}
+		if e := c.(Parameterized).SetParameters(name, p); e != nil {
+			t.Fatal(e)
+		}
+		b, e := NewBundle(name, &CellConfig{Input: 5, Hidden: 8, Output: 3})
+		if e != nil || len(b.Parameters) != len(p) {
+			t.Fatalf("%s is not registered properly: %v", name, e)
+		}
+
+		cell, e := AsRecurrentCell(c)
+		if e != nil {
+			t.Fatal(e)
+		}
+		// huge inputs do not blow normalized cells
+		x := []*Matrix{RandMatMD(5, 1, 0, 100), RandMatMD(5, 1, 0, 100), RandMatMD(5, 1, 0, 100)}
+		G := &Graph{NeedsBackprop: true}
+		ret := Unroll(G, cell, nil, x, nil)
+		for i := range ret.Output.DW {
+			ret.Output.DW[i] = 1
+		}
+		G.Backward()
+		for k, m := range p {
+			for _, v := range m.DW {
+				if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+					t.Fatalf("%s has non finite gradient", k)
+				}
+			}
+		}
+	}
+
+	// with zero input and hidden state gates see memory through peepholes only
+	lstm := MakePeepholeLSTM(2, 4, 1)
+	cPrev := RandMat(4, 1)
+	h, c, _ := lstm.Step(&Graph{}, Mat(2, 1), Mat(4, 1), cPrev)
+	sigmoid := func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }
+	for k := range h.W {
+		cp := float64(cPrev.W[k])
+		f := sigmoid(float64(lstm.Pf.W[k])*cp + float64(lstm.Bf.W[k]))
+		i := sigmoid(float64(lstm.Pi.W[k])*cp + float64(lstm.Bi.W[k]))
+		ck := f*cp + i*math.Tanh(float64(lstm.Bc.W[k]))
+		o := sigmoid(float64(lstm.Po.W[k])*ck + float64(lstm.Bo.W[k]))
+		if math.Abs(ck-float64(c.W[k])) > 1e-5 || math.Abs(o*math.Tanh(ck)-float64(h.W[k])) > 1e-5 {
+			t.Fatalf("unit %d of peephole LSTM has memory %f and output %f instead of %f and %f", k, c.W[k], h.W[k], ck, o*math.Tanh(ck))
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package gortex
 
 import (
-	"fmt"
 	"github.com/vseledkin/gortex/assembler"
 )
 
@@ -25,6 +24,8 @@ type LSTM struct {
 	Bc *Matrix
 
 	Who *Matrix
+
+	Dropout *RecurrentDropout // optional regularization of recurrent connections
 }
 
 func (lstm *LSTM) ForgetGateTrick(v float32) {
@@ -33,25 +34,26 @@ func (lstm *LSTM) ForgetGateTrick(v float32) {
 	}
 }
 
-func MakeLSTM(x_size, h_size, out_size int) *LSTM {
+func MakeLSTM(x_size, h_size, out_size int, options ...InitOption) *LSTM {
 	rnn := new(LSTM)
-	rnn.Wf = RandXavierMat(h_size, x_size)
-	rnn.Uf = RandXavierMat(h_size, h_size)
-	rnn.Bf = RandXavierMat(h_size, 1) // forget gate bias initialization trick will be applied here
+	in := xavier(options)
+	rnn.Wf = in.weights(h_size, x_size)
+	rnn.Uf = in.recurrent(h_size, h_size)
+	rnn.Bf = in.biases(h_size) // forget gate bias initialization trick will be applied here
 
-	rnn.Wi = RandXavierMat(h_size, x_size)
-	rnn.Ui = RandXavierMat(h_size, h_size)
-	rnn.Bi = RandXavierMat(h_size, 1)
+	rnn.Wi = in.weights(h_size, x_size)
+	rnn.Ui = in.recurrent(h_size, h_size)
+	rnn.Bi = in.biases(h_size)
 
-	rnn.Wo = RandXavierMat(h_size, x_size)
-	rnn.Uo = RandXavierMat(h_size, h_size)
-	rnn.Bo = RandXavierMat(h_size, 1)
+	rnn.Wo = in.weights(h_size, x_size)
+	rnn.Uo = in.recurrent(h_size, h_size)
+	rnn.Bo = in.biases(h_size)
 
-	rnn.Wc = RandXavierMat(h_size, x_size)
-	rnn.Uc = RandXavierMat(h_size, h_size)
-	rnn.Bc = RandXavierMat(h_size, 1)
+	rnn.Wc = in.weights(h_size, x_size)
+	rnn.Uc = in.recurrent(h_size, h_size)
+	rnn.Bc = in.biases(h_size)
 
-	rnn.Who = RandXavierMat(out_size, h_size)
+	rnn.Who = in.weights(out_size, h_size)
 	return rnn
 }
 
@@ -78,26 +80,20 @@ func (rnn *LSTM) GetParameters(namespace string) map[string]*Matrix {
 }
 
 func (rnn *LSTM) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			v.W = m.W
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *LSTM) Step(g *Graph, x, h_prev, c_prev *Matrix) (h, c, y *Matrix) {
 	// make LSTM computation graph at one time-step
-	f := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wf, x), g.Mul(rnn.Uf, h_prev)), rnn.Bf))
-	i := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wi, x), g.Mul(rnn.Ui, h_prev)), rnn.Bi))
-	o := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wo, x), g.Mul(rnn.Uo, h_prev)), rnn.Bo))
-	c = g.Tanh(g.Add(g.Add(g.Mul(rnn.Wc, x), g.Mul(rnn.Uc, h_prev)), rnn.Bc))
+	rd := rnn.Dropout
+	x, hd := rd.inputs(g, rnn, x, h_prev)
+	f := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wf, x), g.Mul(rd.recurrent(g, rnn.Uf), hd)), rnn.Bf))
+	i := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wi, x), g.Mul(rd.recurrent(g, rnn.Ui), hd)), rnn.Bi))
+	o := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wo, x), g.Mul(rd.recurrent(g, rnn.Uo), hd)), rnn.Bo))
+	c = g.Tanh(g.Add(g.Add(g.Mul(rnn.Wc, x), g.Mul(rd.recurrent(g, rnn.Uc), hd)), rnn.Bc))
 	c = g.Add(g.EMul(f, c_prev), g.EMul(i, c))
 	h = g.EMul(o, g.Tanh(c))
+	h, c = rd.zoneout(g, h_prev, h, c_prev, c)
 
 	y = g.Mul(rnn.Who, h)
 	return
@@ -0,0 +1,64 @@
+package gortex
+
+/*
+	Minimal Gated Unit https://arxiv.org/abs/1603.09420
+	Guo-Bing Zhou, Jianxin Wu, Chen-Lin Zhang, Zhi-Hua Zhou
+	"Minimal Gated Unit for Recurrent Neural Networks"
+
+	GRU with single forget gate serving as both update and reset gate
+*/
+
+type MGU struct {
+	Wf *Matrix
+	Uf *Matrix
+	Bf *Matrix
+
+	Wh *Matrix
+	Uh *Matrix
+	Bh *Matrix
+
+	Who *Matrix
+}
+
+func MakeMGU(x_size, h_size, out_size int, options ...InitOption) *MGU {
+	rnn := new(MGU)
+	in := xavier(options)
+	rnn.Wf = in.weights(h_size, x_size)
+	rnn.Uf = in.recurrent(h_size, h_size)
+	rnn.Bf = in.biases(h_size)
+
+	rnn.Wh = in.weights(h_size, x_size)
+	rnn.Uh = in.recurrent(h_size, h_size)
+	rnn.Bh = in.biases(h_size)
+
+	rnn.Who = in.weights(out_size, h_size)
+	return rnn
+}
+
+func (rnn *MGU) GetParameters(namespace string) map[string]*Matrix {
+	return map[string]*Matrix{
+		namespace + "_Wf": rnn.Wf,
+		namespace + "_Uf": rnn.Uf,
+		namespace + "_Bf": rnn.Bf,
+
+		namespace + "_Wh": rnn.Wh,
+		namespace + "_Uh": rnn.Uh,
+		namespace + "_Bh": rnn.Bh,
+
+		namespace + "_Who": rnn.Who,
+	}
+}
+
+func (rnn *MGU) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(rnn.GetParameters(namespace), parameters)
+}
+
+func (rnn *MGU) Step(g *Graph, x, h_prev *Matrix) (h, y *Matrix) {
+	// make MGU computation graph at one time-step
+	f := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wf, x), g.Mul(rnn.Uf, h_prev)), rnn.Bf))
+	ht := g.Tanh(g.Add(g.Add(g.Mul(rnn.Wh, x), g.Mul(rnn.Uh, g.EMul(f, h_prev))), rnn.Bh))
+	h = g.Add(g.EMul(g.Sub(f.OnesAs(), f), h_prev), g.EMul(f, ht))
+
+	y = g.Mul(rnn.Who, g.Tanh(h))
+	return
+}
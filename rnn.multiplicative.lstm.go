@@ -1,8 +1,6 @@
 package gortex
 
 import (
-	"fmt"
-
 	"github.com/vseledkin/gortex/assembler"
 )
 
@@ -29,6 +27,8 @@ type MultiplicativeLSTM struct {
 	Bc *Matrix
 
 	Who *Matrix
+
+	Dropout *RecurrentDropout // optional regularization of recurrent connections
 }
 
 func (lstm *MultiplicativeLSTM) ForgetGateTrick(v float32) {
@@ -37,28 +37,29 @@ func (lstm *MultiplicativeLSTM) ForgetGateTrick(v float32) {
 	}
 }
 
-func MakeMultiplicativeLSTM(x_size, h_size, out_size int) *MultiplicativeLSTM {
+func MakeMultiplicativeLSTM(x_size, h_size, out_size int, options ...InitOption) *MultiplicativeLSTM {
 	rnn := new(MultiplicativeLSTM)
-	rnn.Wmx = LSUVMat(h_size, x_size)
-	rnn.Umh = LSUVMat(h_size, h_size)
+	in := NewInitialization(lsuvMat, lsuvMat, Constant{}, options...)
+	rnn.Wmx = in.weights(h_size, x_size)
+	rnn.Umh = in.recurrent(h_size, h_size)
 
-	rnn.Wf = LSUVMat(h_size, x_size)
-	rnn.Uf = LSUVMat(h_size, h_size)
-	rnn.Bf = Mat(h_size, 1) // forget gate bias initialization trick will be applied here
+	rnn.Wf = in.weights(h_size, x_size)
+	rnn.Uf = in.recurrent(h_size, h_size)
+	rnn.Bf = in.biases(h_size) // forget gate bias initialization trick will be applied here
 
-	rnn.Wi = LSUVMat(h_size, x_size)
-	rnn.Ui = LSUVMat(h_size, h_size)
-	rnn.Bi = Mat(h_size, 1)
+	rnn.Wi = in.weights(h_size, x_size)
+	rnn.Ui = in.recurrent(h_size, h_size)
+	rnn.Bi = in.biases(h_size)
 
-	rnn.Wo = LSUVMat(h_size, x_size)
-	rnn.Uo = LSUVMat(h_size, h_size)
-	rnn.Bo = Mat(h_size, 1)
+	rnn.Wo = in.weights(h_size, x_size)
+	rnn.Uo = in.recurrent(h_size, h_size)
+	rnn.Bo = in.biases(h_size)
 
-	rnn.Wc = LSUVMat(h_size, x_size)
-	rnn.Uc = LSUVMat(h_size, h_size)
-	rnn.Bc = Mat(h_size, 1)
+	rnn.Wc = in.weights(h_size, x_size)
+	rnn.Uc = in.recurrent(h_size, h_size)
+	rnn.Bc = in.biases(h_size)
 
-	rnn.Who = LSUVMat(out_size, h_size)
+	rnn.Who = in.weights(out_size, h_size)
 	return rnn
 }
 
@@ -88,28 +89,21 @@ func (rnn *MultiplicativeLSTM) GetParameters(namespace string) map[string]*Matri
 }
 
 func (rnn *MultiplicativeLSTM) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *MultiplicativeLSTM) Step(g *Graph, x, h_prev, c_prev *Matrix) (h, c, y *Matrix) {
 	// make MultiplicativeLSTM computation graph at one time-step
-
-	m := g.EMul(g.Mul(rnn.Wmx, x), g.Mul(rnn.Umh, h_prev))
-	f := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wf, x), g.Mul(rnn.Uf, m)), rnn.Bf))
-	i := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wi, x), g.Mul(rnn.Ui, m)), rnn.Bi))
-	o := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wo, x), g.Mul(rnn.Uo, m)), rnn.Bo))
-	c = g.Tanh(g.Add(g.Add(g.Mul(rnn.Wc, x), g.Mul(rnn.Uc, m)), rnn.Bc))
+	rd := rnn.Dropout
+	x, hd := rd.inputs(g, rnn, x, h_prev)
+	m := g.EMul(g.Mul(rnn.Wmx, x), g.Mul(rd.recurrent(g, rnn.Umh), hd))
+	f := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wf, x), g.Mul(rd.recurrent(g, rnn.Uf), m)), rnn.Bf))
+	i := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wi, x), g.Mul(rd.recurrent(g, rnn.Ui), m)), rnn.Bi))
+	o := g.Sigmoid(g.Add(g.Add(g.Mul(rnn.Wo, x), g.Mul(rd.recurrent(g, rnn.Uo), m)), rnn.Bo))
+	c = g.Tanh(g.Add(g.Add(g.Mul(rnn.Wc, x), g.Mul(rd.recurrent(g, rnn.Uc), m)), rnn.Bc))
 	c = g.Add(g.EMul(f, c_prev), g.EMul(i, c))
 	h = g.EMul(o, g.Tanh(c))
+	h, c = rd.zoneout(g, h_prev, h, c_prev, c)
 
 	y = g.Mul(rnn.Who, h)
 	return
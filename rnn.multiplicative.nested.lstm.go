@@ -1,8 +1,6 @@
 package gortex
 
 import (
-	"fmt"
-
 	"github.com/vseledkin/gortex/assembler"
 )
 
@@ -39,30 +37,31 @@ func (lstm *MultiplicativeNestedLSTM) ForgetGateTrick(v float32) {
 	}
 }
 
-func MakeMultiplicativeNestedLSTM(x_size, h_size, out_size int) *MultiplicativeNestedLSTM {
+func MakeMultiplicativeNestedLSTM(x_size, h_size, out_size int, options ...InitOption) *MultiplicativeNestedLSTM {
 	rnn := new(MultiplicativeNestedLSTM)
-	rnn.Wmx = RandXavierMat(h_size, x_size)
-	rnn.Umh = RandXavierMat(h_size, h_size)
+	in := xavier(options)
+	rnn.Wmx = in.weights(h_size, x_size)
+	rnn.Umh = in.recurrent(h_size, h_size)
 
-	rnn.Wf = RandXavierMat(h_size, x_size)
-	rnn.Uf = RandXavierMat(h_size, h_size)
-	rnn.Bf = RandXavierMat(h_size, 1) // forget gate bias initialization trick will be applied here
+	rnn.Wf = in.weights(h_size, x_size)
+	rnn.Uf = in.recurrent(h_size, h_size)
+	rnn.Bf = in.biases(h_size) // forget gate bias initialization trick will be applied here
 
-	rnn.Wi = RandXavierMat(h_size, x_size)
-	rnn.Ui = RandXavierMat(h_size, h_size)
-	rnn.Bi = RandXavierMat(h_size, 1)
+	rnn.Wi = in.weights(h_size, x_size)
+	rnn.Ui = in.recurrent(h_size, h_size)
+	rnn.Bi = in.biases(h_size)
 
-	rnn.Wo = RandXavierMat(h_size, x_size)
-	rnn.Uo = RandXavierMat(h_size, h_size)
-	rnn.Bo = RandXavierMat(h_size, 1)
+	rnn.Wo = in.weights(h_size, x_size)
+	rnn.Uo = in.recurrent(h_size, h_size)
+	rnn.Bo = in.biases(h_size)
 
-	rnn.Wc = RandXavierMat(h_size, x_size)
-	rnn.Uc = RandXavierMat(h_size, h_size)
-	rnn.Bc = RandXavierMat(h_size, 1)
+	rnn.Wc = in.weights(h_size, x_size)
+	rnn.Uc = in.recurrent(h_size, h_size)
+	rnn.Bc = in.biases(h_size)
 
-	rnn.Who = RandXavierMat(out_size, h_size)
+	rnn.Who = in.weights(out_size, h_size)
 
-	rnn.innerMemory = MakeMultiplicativeLSTM(h_size, h_size, h_size)
+	rnn.innerMemory = MakeMultiplicativeLSTM(h_size, h_size, h_size, options...)
 	return rnn
 }
 
@@ -97,16 +96,7 @@ func (rnn *MultiplicativeNestedLSTM) GetParameters(namespace string) map[string]
 }
 
 func (rnn *MultiplicativeNestedLSTM) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *MultiplicativeNestedLSTM) Step(g *Graph, x, h_prev, c_prev, c_previn *Matrix) (h, c, cin, y *Matrix) {
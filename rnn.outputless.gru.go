@@ -1,6 +1,5 @@
 package gortex
 
-import "fmt"
 import "github.com/vseledkin/gortex/assembler"
 
 // Gated recurrent unit
@@ -27,19 +26,20 @@ func (gru *OutputlessGRU) ForgetGateTrick(v float32) {
 	}
 }
 
-func MakeOutputlessGRU(x_size, h_size int) *OutputlessGRU {
+func MakeOutputlessGRU(x_size, h_size int, options ...InitOption) *OutputlessGRU {
 	rnn := new(OutputlessGRU)
-	rnn.Wz = RandXavierMat(h_size, x_size)
-	rnn.Uz = RandXavierMat(h_size, h_size)
-	rnn.Bz = RandXavierMat(h_size, 1)
+	in := xavier(options)
+	rnn.Wz = in.weights(h_size, x_size)
+	rnn.Uz = in.recurrent(h_size, h_size)
+	rnn.Bz = in.biases(h_size)
 
-	rnn.Wr = RandXavierMat(h_size, x_size)
-	rnn.Ur = RandXavierMat(h_size, h_size)
-	rnn.Br = RandXavierMat(h_size, 1)
+	rnn.Wr = in.weights(h_size, x_size)
+	rnn.Ur = in.recurrent(h_size, h_size)
+	rnn.Br = in.biases(h_size)
 
-	rnn.Wh = RandXavierMat(h_size, x_size)
-	rnn.Uh = RandXavierMat(h_size, h_size)
-	rnn.Bh = RandXavierMat(h_size, 1)
+	rnn.Wh = in.weights(h_size, x_size)
+	rnn.Uh = in.recurrent(h_size, h_size)
+	rnn.Bh = in.biases(h_size)
 
 	return rnn
 }
@@ -61,16 +61,7 @@ func (rnn *OutputlessGRU) GetParameters(namespace string) map[string]*Matrix {
 }
 
 func (rnn *OutputlessGRU) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *OutputlessGRU) Step(g *Graph, x, h_prev *Matrix) (h *Matrix) {
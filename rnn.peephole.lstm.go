@@ -0,0 +1,108 @@
+package gortex
+
+import (
+	"github.com/vseledkin/gortex/assembler"
+)
+
+// Long Short Term Memory cell with peephole connections
+// Felix A. Gers, Jurgen Schmidhuber "Recurrent nets that time and count"
+// forget and input gates look at previous memory, output gate at the new one
+
+type PeepholeLSTM struct {
+	Wf *Matrix
+	Uf *Matrix
+	Pf *Matrix
+	Bf *Matrix
+
+	Wi *Matrix
+	Ui *Matrix
+	Pi *Matrix
+	Bi *Matrix
+
+	Wo *Matrix
+	Uo *Matrix
+	Po *Matrix
+	Bo *Matrix
+
+	Wc *Matrix
+	Uc *Matrix
+	Bc *Matrix
+
+	Who *Matrix
+}
+
+func (lstm *PeepholeLSTM) ForgetGateTrick(v float32) {
+	if lstm.Bf != nil {
+		assembler.Sset(v, lstm.Bf.W)
+	}
+}
+
+// MakePeepholeLSTM makes cell with Xavier weights, peepholes Pf, Pi and Po are vectors
+// and take WithBiases initializer like biases do
+func MakePeepholeLSTM(x_size, h_size, out_size int, options ...InitOption) *PeepholeLSTM {
+	rnn := new(PeepholeLSTM)
+	in := xavier(options)
+	rnn.Wf = in.weights(h_size, x_size)
+	rnn.Uf = in.recurrent(h_size, h_size)
+	rnn.Pf = in.biases(h_size)
+	rnn.Bf = in.biases(h_size) // forget gate bias initialization trick will be applied here
+
+	rnn.Wi = in.weights(h_size, x_size)
+	rnn.Ui = in.recurrent(h_size, h_size)
+	rnn.Pi = in.biases(h_size)
+	rnn.Bi = in.biases(h_size)
+
+	rnn.Wo = in.weights(h_size, x_size)
+	rnn.Uo = in.recurrent(h_size, h_size)
+	rnn.Po = in.biases(h_size)
+	rnn.Bo = in.biases(h_size)
+
+	rnn.Wc = in.weights(h_size, x_size)
+	rnn.Uc = in.recurrent(h_size, h_size)
+	rnn.Bc = in.biases(h_size)
+
+	rnn.Who = in.weights(out_size, h_size)
+	return rnn
+}
+
+func (rnn *PeepholeLSTM) GetParameters(namespace string) map[string]*Matrix {
+	return map[string]*Matrix{
+		namespace + "_Wf": rnn.Wf,
+		namespace + "_Uf": rnn.Uf,
+		namespace + "_Pf": rnn.Pf,
+		namespace + "_Bf": rnn.Bf,
+
+		namespace + "_Wi": rnn.Wi,
+		namespace + "_Ui": rnn.Ui,
+		namespace + "_Pi": rnn.Pi,
+		namespace + "_Bi": rnn.Bi,
+
+		namespace + "_Wo": rnn.Wo,
+		namespace + "_Uo": rnn.Uo,
+		namespace + "_Po": rnn.Po,
+		namespace + "_Bo": rnn.Bo,
+
+		namespace + "_Wc": rnn.Wc,
+		namespace + "_Uc": rnn.Uc,
+		namespace + "_Bc": rnn.Bc,
+
+		namespace + "_Who": rnn.Who,
+	}
+}
+
+func (rnn *PeepholeLSTM) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(rnn.GetParameters(namespace), parameters)
+}
+
+func (rnn *PeepholeLSTM) Step(g *Graph, x, h_prev, c_prev *Matrix) (h, c, y *Matrix) {
+	// make peephole LSTM computation graph at one time-step
+	f := g.Sigmoid(g.Add(g.Add(g.Add(g.Mul(rnn.Wf, x), g.Mul(rnn.Uf, h_prev)), g.EMul(rnn.Pf, c_prev)), rnn.Bf))
+	i := g.Sigmoid(g.Add(g.Add(g.Add(g.Mul(rnn.Wi, x), g.Mul(rnn.Ui, h_prev)), g.EMul(rnn.Pi, c_prev)), rnn.Bi))
+	c = g.Tanh(g.Add(g.Add(g.Mul(rnn.Wc, x), g.Mul(rnn.Uc, h_prev)), rnn.Bc))
+	c = g.Add(g.EMul(f, c_prev), g.EMul(i, c))
+	o := g.Sigmoid(g.Add(g.Add(g.Add(g.Mul(rnn.Wo, x), g.Mul(rnn.Uo, h_prev)), g.EMul(rnn.Po, c)), rnn.Bo))
+	h = g.EMul(o, g.Tanh(c))
+
+	y = g.Mul(rnn.Who, h)
+	return
+}
@@ -0,0 +1,129 @@
+package gortex
+
+import (
+	"github.com/vseledkin/gortex/assembler"
+)
+
+/*
+	Quasi-Recurrent Neural Network https://arxiv.org/abs/1611.01576
+	James Bradbury, Stephen Merity, Caiming Xiong, Richard Socher
+
+	convolution of width 2 over inputs followed by fo-pooling,
+	convolutions of whole sequence are made by one matrix multiplication per weight
+*/
+
+type QRNN struct {
+	// W multiplies current input, V previous one
+	Wz *Matrix
+	Vz *Matrix
+	Bz *Matrix
+
+	Wf *Matrix
+	Vf *Matrix
+	Bf *Matrix
+
+	Wo *Matrix
+	Vo *Matrix
+	Bo *Matrix
+
+	Who *Matrix
+}
+
+func (qrnn *QRNN) ForgetGateTrick(v float32) {
+	if qrnn.Bf != nil {
+		assembler.Sset(v, qrnn.Bf.W)
+	}
+}
+
+func MakeQRNN(x_size, h_size, out_size int, options ...InitOption) *QRNN {
+	rnn := new(QRNN)
+	in := xavier(options)
+	rnn.Wz = in.weights(h_size, x_size)
+	rnn.Vz = in.weights(h_size, x_size)
+	rnn.Bz = in.biases(h_size)
+
+	rnn.Wf = in.weights(h_size, x_size)
+	rnn.Vf = in.weights(h_size, x_size)
+	rnn.Bf = in.biases(h_size) // forget gate bias initialization trick will be applied here
+
+	rnn.Wo = in.weights(h_size, x_size)
+	rnn.Vo = in.weights(h_size, x_size)
+	rnn.Bo = in.biases(h_size)
+
+	rnn.Who = in.weights(out_size, h_size)
+	return rnn
+}
+
+func (rnn *QRNN) GetParameters(namespace string) map[string]*Matrix {
+	return map[string]*Matrix{
+		namespace + "_Wz": rnn.Wz,
+		namespace + "_Vz": rnn.Vz,
+		namespace + "_Bz": rnn.Bz,
+
+		namespace + "_Wf": rnn.Wf,
+		namespace + "_Vf": rnn.Vf,
+		namespace + "_Bf": rnn.Bf,
+
+		namespace + "_Wo": rnn.Wo,
+		namespace + "_Vo": rnn.Vo,
+		namespace + "_Bo": rnn.Bo,
+
+		namespace + "_Who": rnn.Who,
+	}
+}
+
+func (rnn *QRNN) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(rnn.GetParameters(namespace), parameters)
+}
+
+// pooling is fo-pooling of one step given convolutions of inputs
+func (rnn *QRNN) pooling(g *Graph, z, f, o, c_prev *Matrix) (h, c *Matrix) {
+	z = g.Tanh(g.Add(z, rnn.Bz))
+	f = g.Sigmoid(g.Add(f, rnn.Bf))
+	o = g.Sigmoid(g.Add(o, rnn.Bo))
+	c = g.Add(g.EMul(f, c_prev), g.EMul(g.Sub(f.OnesAs(), f), z))
+	h = g.EMul(o, c)
+	return
+}
+
+func (rnn *QRNN) Step(g *Graph, x, x_prev, c_prev *Matrix) (h, c, y *Matrix) {
+	// make QRNN computation graph at one time-step
+	z := g.Add(g.Mul(rnn.Wz, x), g.Mul(rnn.Vz, x_prev))
+	f := g.Add(g.Mul(rnn.Wf, x), g.Mul(rnn.Vf, x_prev))
+	o := g.Add(g.Mul(rnn.Wo, x), g.Mul(rnn.Vo, x_prev))
+	h, c = rnn.pooling(g, z, f, o, c_prev)
+	y = g.Mul(rnn.Who, h)
+	return
+}
+
+// Sequence runs QRNN over whole sequence, x_prev is input preceding sequence, zero vector at start,
+// empty sequence leaves memory as it is
+func (rnn *QRNN) Sequence(g *Graph, x []*Matrix, x_prev, c_prev *Matrix) (h []*Matrix, c *Matrix, y []*Matrix) {
+	if len(x) == 0 {
+		return nil, c_prev, nil
+	}
+	packed := g.PackColumnVectors(x)
+	shifted := g.PackColumnVectors(append([]*Matrix{x_prev}, x[:len(x)-1]...))
+	convolve := func(w, v *Matrix) []*Matrix {
+		return g.UnpackColumnVectors(g.Add(g.Mul(w, packed), g.Mul(v, shifted)))
+	}
+	z, f, o := convolve(rnn.Wz, rnn.Vz), convolve(rnn.Wf, rnn.Vf), convolve(rnn.Wo, rnn.Vo)
+	h = make([]*Matrix, len(x))
+	c = c_prev
+	for t := range x {
+		h[t], c = rnn.pooling(g, z[t], f[t], o[t], c)
+	}
+	y = project(g, rnn.Who, g.PackColumnVectors(h))
+	return
+}
+
+// ForwardSequence runs QRNN from zero memory and input
+func (rnn *QRNN) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	_, _, y := rnn.Sequence(g, x, Mat(rnn.Wz.Columns, 1), Mat(rnn.Wz.Rows, 1))
+	return y
+}
+
+// ForwardMasked runs QRNN step by step skipping padding steps
+func (rnn *QRNN) ForwardMasked(g *Graph, x []*Matrix, mask []bool) []*Matrix {
+	return cellLayer{qrnnCell{rnn}}.ForwardMasked(g, x, mask)
+}
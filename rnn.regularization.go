@@ -0,0 +1,128 @@
+package gortex
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+/*
+	Regularization of recurrent connections, masks are sampled once per graph
+	so graph is expected to run one sequence as usual
+
+	Variational dropout: Yarin Gal, Zoubin Ghahramani https://arxiv.org/abs/1512.05287
+	Zoneout: David Krueger et al. https://arxiv.org/abs/1606.01305
+	Weight dropped LSTM: Stephen Merity, Nitish Shirish Keskar, Richard Socher https://arxiv.org/abs/1708.02182
+*/
+
+// RecurrentDropout configures regularization of LSTM, GRU and MultiplicativeLSTM,
+// it is applied only when graph NeedsBackprop, nil or zero probabilities turn it off
+type RecurrentDropout struct {
+	Input      float32 // variational dropout of x, one mask for all steps
+	Hidden     float32 // variational dropout of h_prev, one mask for all steps
+	ZoneoutH   float32 // probability to keep previous value of every hidden unit at every step
+	ZoneoutC   float32 // probability to keep previous value of every memory unit at every step
+	WeightDrop float32 // DropConnect probability of recurrent U matrices, one mask for all steps
+}
+
+type maskKey struct {
+	owner interface{}
+	name  string
+}
+
+// sequenceMask returns inverted dropout mask sampled on first call for owner and name in this graph
+func (g *Graph) sequenceMask(owner interface{}, name string, size int, probability float32) *Matrix {
+	key := maskKey{owner, name}
+	if m, ok := g.masks[key]; ok {
+		if len(m.W) != size {
+			panic(fmt.Errorf("%s mask of size %d is reused for size %d", name, len(m.W), size))
+		}
+		return m
+	}
+	if g.masks == nil {
+		g.masks = make(map[maskKey]*Matrix)
+	}
+	m := Mat(size, 1)
+	scale := 1 / (1 - probability)
+	for i := range m.W {
+		if rand.Float32() >= probability {
+			m.W[i] = scale
+		}
+	}
+	g.masks[key] = m
+	return m
+}
+
+// dropConnect returns u with dropped weights, one for all steps of graph,
+// gradients of all steps are passed to u at once
+func (g *Graph) dropConnect(u *Matrix, probability float32) *Matrix {
+	mask := g.sequenceMask(u, "DropConnect", len(u.W), probability)
+	key := maskKey{u, "DropConnected"}
+	if out, ok := g.masks[key]; ok {
+		return out
+	}
+	out := Mat(u.Rows, u.Columns)
+	for i := range out.W {
+		out.W[i] = u.W[i] * mask.W[i]
+	}
+	g.masks[key] = out
+	g.backprop = append(g.backprop, func() {
+		for i := range out.DW {
+			u.DW[i] += out.DW[i] * mask.W[i]
+		}
+	})
+	return out
+}
+
+// inputs applies variational dropout to inputs of owner cell step
+func (rd *RecurrentDropout) inputs(g *Graph, owner interface{}, x, h_prev *Matrix) (*Matrix, *Matrix) {
+	if rd == nil || !g.NeedsBackprop {
+		return x, h_prev
+	}
+	if rd.Input > 0 {
+		x = g.EMul(x, g.sequenceMask(owner, "Input", x.Rows, rd.Input))
+	}
+	if rd.Hidden > 0 {
+		h_prev = g.EMul(h_prev, g.sequenceMask(owner, "Hidden", h_prev.Rows, rd.Hidden))
+	}
+	return x, h_prev
+}
+
+// recurrent returns recurrent matrix to use in this graph
+func (rd *RecurrentDropout) recurrent(g *Graph, u *Matrix) *Matrix {
+	if rd == nil || !g.NeedsBackprop || rd.WeightDrop <= 0 {
+		return u
+	}
+	return g.dropConnect(u, rd.WeightDrop)
+}
+
+// zoneout mixes previous and next value of units, during inference expected value is used as in paper
+func zoneout(g *Graph, probability float32, prev, next *Matrix) *Matrix {
+	if probability <= 0 {
+		return next
+	}
+	if !g.NeedsBackprop {
+		return g.Add(g.MulConstant(probability, prev), g.MulConstant(1-probability, next))
+	}
+	keep := Mat(prev.Rows, 1)
+	update := Mat(prev.Rows, 1)
+	for i := range keep.W {
+		if rand.Float32() < probability {
+			keep.W[i] = 1
+		} else {
+			update.W[i] = 1
+		}
+	}
+	return g.Add(g.EMul(keep, prev), g.EMul(update, next))
+}
+
+// zoneout applies zoneout to hidden state and memory, c may be nil for cells without memory
+func (rd *RecurrentDropout) zoneout(g *Graph, h_prev, h, c_prev, c *Matrix) (*Matrix, *Matrix) {
+	if rd == nil {
+		return h, c
+	}
+	h = zoneout(g, rd.ZoneoutH, h_prev, h)
+	if c != nil {
+		c = zoneout(g, rd.ZoneoutC, c_prev, c)
+	}
+	return h, c
+}
@@ -0,0 +1,103 @@
+package gortex
+
+import "testing"
+
+func TestRecurrentDropoutInference(t *testing.T) {
+	x := []*Matrix{RandMat(5, 1), RandMat(5, 1), RandMat(5, 1)}
+	dropout := &RecurrentDropout{Input: 0.5, Hidden: 0.5, WeightDrop: 0.5}
+	for _, c := range []interface{}{MakeLSTM(5, 6, 2), MakeGRU(5, 6, 2), MakeMultiplicativeLSTM(5, 6, 2)} {
+		cell, _ := AsRecurrentCell(c)
+		expected := Unroll(&Graph{}, cell, nil, x, nil)
+		switch rnn := c.(type) {
+		case *LSTM:
+			rnn.Dropout = dropout
+		case *GRU:
+			rnn.Dropout = dropout
+		case *MultiplicativeLSTM:
+			rnn.Dropout = dropout
+		}
+		actual := Unroll(&Graph{}, cell, nil, x, nil)
+		for i := range x {
+			sameMatrix(t, actual.Outputs[i], expected.Outputs[i], "dropout must be off during inference")
+		}
+		G := &Graph{NeedsBackprop: true}
+		Unroll(G, cell, nil, x, nil)
+		if len(G.masks) == 0 {
+			t.Fatalf("%T does not use dropout during training", c)
+		}
+	}
+
+	// inference zoneout is mix of previous and next state
+	lstm := MakeLSTM(5, 6, 2)
+	G := &Graph{}
+	h, c, _ := lstm.Step(G, x[0], Mat(6, 1), Mat(6, 1))
+	lstm.Dropout = &RecurrentDropout{ZoneoutH: 0.25, ZoneoutC: 0.5}
+	hz, cz, _ := lstm.Step(G, x[0], Mat(6, 1), Mat(6, 1))
+	for i := range h.W {
+		if d := hz.W[i] - 0.75*h.W[i]; d > 1e-6 || d < -1e-6 {
+			t.Fatalf("hidden unit %d is %f instead of %f", i, hz.W[i], 0.75*h.W[i])
+		}
+		if d := cz.W[i] - 0.5*c.W[i]; d > 1e-6 || d < -1e-6 {
+			t.Fatalf("memory unit %d is %f instead of %f", i, cz.W[i], 0.5*c.W[i])
+		}
+	}
+}
+
+func TestRecurrentDropoutMasks(t *testing.T) {
+	lstm := MakeLSTM(50, 40, 2)
+	lstm.Dropout = &RecurrentDropout{Input: 0.3, Hidden: 0.3, WeightDrop: 0.4}
+	cell, _ := AsRecurrentCell(lstm)
+	G := &Graph{NeedsBackprop: true}
+	x := []*Matrix{RandMat(50, 1), RandMat(50, 1), RandMat(50, 1), RandMat(50, 1)}
+	ret := Unroll(G, cell, nil, x, nil)
+	// one mask for x, h and every U and one dropped copy of every U for whole sequence
+	if len(G.masks) != 2+2*4 {
+		t.Fatalf("sampled %d masks instead of 10", len(G.masks))
+	}
+	mask := G.masks[maskKey{lstm, "Input"}]
+	dropped := 0
+	for _, v := range mask.W {
+		switch v {
+		case 0:
+			dropped++
+		case 1 / (1 - float32(0.3)):
+		default:
+			t.Fatalf("mask value %f", v)
+		}
+	}
+	if dropped == 0 || dropped == len(mask.W) {
+		t.Fatalf("dropped %d of %d inputs", dropped, len(mask.W))
+	}
+
+	for _, y := range ret.Outputs {
+		for i := range y.DW {
+			y.DW[i] = 1
+		}
+	}
+	G.Backward()
+	// dropped recurrent weights get no gradient
+	connections := G.masks[maskKey{lstm.Uf, "DropConnect"}]
+	zero, nonzero := 0, 0
+	for i, m := range connections.W {
+		if m == 0 {
+			if lstm.Uf.DW[i] != 0 {
+				t.Fatal("dropped weight has gradient")
+			}
+			zero++
+		} else if lstm.Uf.DW[i] != 0 {
+			nonzero++
+		}
+	}
+	if zero == 0 || nonzero == 0 {
+		t.Fatalf("%d weights are dropped and %d have gradients", zero, nonzero)
+	}
+}
+
+func TestZoneoutTraining(t *testing.T) {
+	gru := MakeGRU(3, 200, 2)
+	gru.Dropout = &RecurrentDropout{ZoneoutH: 1}
+	G := &Graph{NeedsBackprop: true}
+	h := RandMat(200, 1)
+	next, _ := gru.Step(G, RandMat(3, 1), h)
+	sameMatrix(t, next, h, "all units must keep previous values")
+}
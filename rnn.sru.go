@@ -0,0 +1,121 @@
+package gortex
+
+import (
+	"github.com/vseledkin/gortex/assembler"
+)
+
+/*
+	Simple Recurrent Unit https://arxiv.org/abs/1709.02755
+	Tao Lei, Yu Zhang, Sida I. Wang, Hui Dai, Yoav Artzi
+	"Simple Recurrent Units for Highly Parallelizable Recurrence"
+
+	gates do not depend on previous state so input projections of whole sequence
+	are made by one matrix multiplication, recurrence is element wise only
+*/
+
+type SRU struct {
+	W  *Matrix
+	Wf *Matrix
+	Bf *Matrix
+	Wr *Matrix
+	Br *Matrix
+	Ws *Matrix // highway projection of x, nil when x and h sizes are equal
+
+	Who *Matrix
+}
+
+func (sru *SRU) ForgetGateTrick(v float32) {
+	if sru.Bf != nil {
+		assembler.Sset(v, sru.Bf.W)
+	}
+}
+
+func MakeSRU(x_size, h_size, out_size int, options ...InitOption) *SRU {
+	rnn := new(SRU)
+	in := xavier(options)
+	rnn.W = in.weights(h_size, x_size)
+	rnn.Wf = in.weights(h_size, x_size)
+	rnn.Bf = in.biases(h_size) // forget gate bias initialization trick will be applied here
+	rnn.Wr = in.weights(h_size, x_size)
+	rnn.Br = in.biases(h_size)
+	if x_size != h_size {
+		rnn.Ws = in.weights(h_size, x_size)
+	}
+	rnn.Who = in.weights(out_size, h_size)
+	return rnn
+}
+
+func (rnn *SRU) GetParameters(namespace string) map[string]*Matrix {
+	p := map[string]*Matrix{
+		namespace + "_W":   rnn.W,
+		namespace + "_Wf":  rnn.Wf,
+		namespace + "_Bf":  rnn.Bf,
+		namespace + "_Wr":  rnn.Wr,
+		namespace + "_Br":  rnn.Br,
+		namespace + "_Who": rnn.Who,
+	}
+	if rnn.Ws != nil {
+		p[namespace+"_Ws"] = rnn.Ws
+	}
+	return p
+}
+
+func (rnn *SRU) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(rnn.GetParameters(namespace), parameters)
+}
+
+// recurrence combines input projections of one step with previous memory
+func (rnn *SRU) recurrence(g *Graph, xt, f, r, s, c_prev *Matrix) (h, c *Matrix) {
+	f = g.Sigmoid(g.Add(f, rnn.Bf))
+	r = g.Sigmoid(g.Add(r, rnn.Br))
+	c = g.Add(g.EMul(f, c_prev), g.EMul(g.Sub(f.OnesAs(), f), xt))
+	h = g.Add(g.EMul(r, g.Tanh(c)), g.EMul(g.Sub(r.OnesAs(), r), s))
+	return
+}
+
+func (rnn *SRU) Step(g *Graph, x, c_prev *Matrix) (h, c, y *Matrix) {
+	// make SRU computation graph at one time-step
+	s := x
+	if rnn.Ws != nil {
+		s = g.Mul(rnn.Ws, x)
+	}
+	h, c = rnn.recurrence(g, g.Mul(rnn.W, x), g.Mul(rnn.Wf, x), g.Mul(rnn.Wr, x), s, c_prev)
+	y = g.Mul(rnn.Who, h)
+	return
+}
+
+// project multiplies m by every vector of packed sequence at once
+func project(g *Graph, m, packed *Matrix) []*Matrix {
+	return g.UnpackColumnVectors(g.Mul(m, packed))
+}
+
+// Sequence runs SRU over whole sequence making input and output projections for all steps at once,
+// empty sequence leaves memory as it is
+func (rnn *SRU) Sequence(g *Graph, x []*Matrix, c_prev *Matrix) (h []*Matrix, c *Matrix, y []*Matrix) {
+	if len(x) == 0 {
+		return nil, c_prev, nil
+	}
+	packed := g.PackColumnVectors(x)
+	xt, f, r, s := project(g, rnn.W, packed), project(g, rnn.Wf, packed), project(g, rnn.Wr, packed), x
+	if rnn.Ws != nil {
+		s = project(g, rnn.Ws, packed)
+	}
+	h = make([]*Matrix, len(x))
+	c = c_prev
+	for t := range x {
+		h[t], c = rnn.recurrence(g, xt[t], f[t], r[t], s[t], c)
+	}
+	y = project(g, rnn.Who, g.PackColumnVectors(h))
+	return
+}
+
+// ForwardSequence runs SRU from zero memory
+func (rnn *SRU) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	_, _, y := rnn.Sequence(g, x, Mat(rnn.W.Rows, 1))
+	return y
+}
+
+// ForwardMasked runs SRU step by step skipping padding steps
+func (rnn *SRU) ForwardMasked(g *Graph, x []*Matrix, mask []bool) []*Matrix {
+	return cellLayer{sruCell{rnn}}.ForwardMasked(g, x, mask)
+}
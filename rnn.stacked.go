@@ -0,0 +1,104 @@
+package gortex
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Stacked runs recurrent layers one on top of another
+type Stacked struct {
+	Layers   []SequenceLayer
+	Dropout  float32 // dropout probability of outputs passed to next layer, applied during training only
+	Residual bool    // add layer input to its output for every layer but first, sizes must match
+}
+
+// MakeStacked stacks cells, BiRNNs or any other layers adapted by AsSequenceLayer
+func MakeStacked(layers ...interface{}) *Stacked {
+	s := &Stacked{Layers: make([]SequenceLayer, len(layers))}
+	for i, layer := range layers {
+		l, e := AsSequenceLayer(layer)
+		if e != nil {
+			panic(fmt.Errorf("layer %d: %v", i, e))
+		}
+		s.Layers[i] = l
+	}
+	return s
+}
+
+func (s *Stacked) GetParameters(namespace string) map[string]*Matrix {
+	p := make(map[string]*Matrix)
+	for i, l := range s.Layers {
+		for k, v := range l.GetParameters(fmt.Sprintf("%s_layer%d", namespace, i)) {
+			p[k] = v
+		}
+	}
+	return p
+}
+
+func (s *Stacked) SetParameters(namespace string, parameters map[string]*Matrix) error {
+	return setParameters(s.GetParameters(namespace), parameters)
+}
+
+func (s *Stacked) ForwardSequence(g *Graph, x []*Matrix) []*Matrix {
+	return s.ForwardMasked(g, x, nil)
+}
+
+// ForwardMasked skips padding steps, all layers must be MaskedSequenceLayer if mask is given
+func (s *Stacked) ForwardMasked(g *Graph, x []*Matrix, mask []bool) []*Matrix {
+	for i, l := range s.Layers {
+		var y []*Matrix
+		if mask == nil {
+			y = l.ForwardSequence(g, x)
+		} else if ml, ok := l.(MaskedSequenceLayer); ok {
+			y = ml.ForwardMasked(g, x, mask)
+		} else {
+			panic(fmt.Errorf("layer %d of type %T can not skip padding steps", i, l))
+		}
+		for t := range y {
+			if y[t] == nil {
+				continue
+			}
+			if s.Residual && i > 0 {
+				y[t] = g.Add(y[t], x[t])
+			}
+			if s.Dropout > 0 && i < len(s.Layers)-1 {
+				y[t] = g.Dropout(s.Dropout, y[t])
+			}
+		}
+		x = y
+	}
+	return x
+}
+
+// StackedConfig makes Stacked from registered architectures
+type StackedConfig struct {
+	Namespace string // parameters namespace, Stacked if empty
+	Layers    []LayerConfig
+	Dropout   float32
+	Residual  bool
+}
+
+func init() {
+	RegisterArchitecture("Stacked", func(config json.RawMessage) (interface{}, map[string]*Matrix, error) {
+		c := new(StackedConfig)
+		if e := json.Unmarshal(config, c); e != nil {
+			return nil, nil, fmt.Errorf("Stacked config: %v", e)
+		}
+		s := &Stacked{Dropout: c.Dropout, Residual: c.Residual}
+		for _, lc := range c.Layers {
+			model, e := buildLayer(lc)
+			if e != nil {
+				return nil, nil, fmt.Errorf("Stacked %v", e)
+			}
+			l, e := AsSequenceLayer(model)
+			if e != nil {
+				return nil, nil, fmt.Errorf("Stacked layer %s: %v", lc.Name, e)
+			}
+			s.Layers = append(s.Layers, l)
+		}
+		if len(c.Namespace) == 0 {
+			c.Namespace = "Stacked"
+		}
+		return s, s.GetParameters(c.Namespace), nil
+	})
+}
@@ -0,0 +1,142 @@
+package gortex
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func TestBiRNN(t *testing.T) {
+	x := []*Matrix{RandMat(4, 1), RandMat(4, 1), RandMat(4, 1)}
+	for _, test := range []struct {
+		merge MergeMode
+		size  int
+	}{{MergeConcat, 10}, {MergeSum, 5}, {MergeMean, 5}} {
+		rnn := MakeBiRNN(MakeOutputlessLSTM(4, 5), MakeOutputlessGRU(4, 5), test.merge)
+		G := &Graph{}
+		y, forward, backward := rnn.Run(G, x, nil)
+		if len(y) != 3 || y[0].Rows != test.size {
+			t.Fatalf("merge %d made %d outputs of size %d", test.merge, len(y), y[0].Rows)
+		}
+		f := Unroll(G, rnn.Forward, nil, x, nil)
+		b := Unroll(G, rnn.Backward, nil, x, &UnrollOp{Reverse: true})
+		sameMatrix(t, forward.H, f.Last.H, "forward state")
+		sameMatrix(t, backward.H, b.States[0].H, "backward state")
+		if test.merge == MergeMean {
+			for i := range y[1].W {
+				if d := y[1].W[i] - (f.Outputs[1].W[i]+b.Outputs[1].W[i])/2; d > 1e-6 || d < -1e-6 {
+					t.Fatal("mean merge is wrong")
+				}
+			}
+		}
+	}
+
+	// padding at the end does not change backward pass over valid steps
+	rnn := MakeBiRNN(MakeGRU(4, 5, 3), MakeGRU(4, 5, 3), MergeConcat)
+	G := &Graph{}
+	padded := rnn.ForwardMasked(G, append(x[:2:2], Mat(4, 1)), []bool{true, true, false})
+	unpadded := rnn.ForwardSequence(G, x[:2])
+	if padded[2] != nil {
+		t.Fatal("padding step must have no output")
+	}
+	sameMatrix(t, padded[0], unpadded[0], "padded output")
+
+	p := rnn.GetParameters("bi")
+	if p["bi_forward_Wz"] == nil || p["bi_backward_Wz"] == nil || len(p) != 20 {
+		t.Fatalf("wrong parameters %d", len(p))
+	}
+}
+
+func TestStacked(t *testing.T) {
+	// 3 layer bidirectional encoder
+	s := MakeStacked(
+		MakeBiRNN(MakeOutputlessLSTM(4, 6), MakeOutputlessLSTM(4, 6), MergeSum),
+		MakeBiRNN(MakeOutputlessLSTM(6, 6), MakeOutputlessLSTM(6, 6), MergeSum),
+		MakeBiRNN(MakeOutputlessLSTM(6, 6), MakeOutputlessLSTM(6, 6), MergeMean),
+	)
+	s.Residual = true
+	s.Dropout = 0.3
+	x := []*Matrix{RandMat(4, 1), RandMat(4, 1), RandMat(4, 1), Mat(4, 1)}
+	mask := []bool{true, true, true, false}
+
+	// dropout is off during inference so runs are repeatable
+	G := &Graph{}
+	y1 := s.ForwardMasked(G, x, mask)
+	y2 := s.ForwardMasked(G, x, mask)
+	if len(y1) != 4 || y1[0].Rows != 6 || y1[3] != nil {
+		t.Fatal("wrong stacked output")
+	}
+	sameMatrix(t, y1[1], y2[1], "inference output")
+
+	G = &Graph{NeedsBackprop: true}
+	y := s.ForwardMasked(G, x, mask)
+	for _, o := range y[:3] {
+		for i := range o.DW {
+			o.DW[i] = 1
+		}
+	}
+	G.Backward()
+	p := s.GetParameters("encoder")
+	if len(p) != 3*2*12 || p["encoder_layer2_backward_Wf"] == nil {
+		t.Fatalf("wrong parameters %d", len(p))
+	}
+	if p["encoder_layer0_forward_Wf"].DW[0] == 0 {
+		t.Fatal("gradient must reach first layer")
+	}
+	if e := s.SetParameters("encoder", p); e != nil {
+		t.Fatal(e)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("layer without masking support must panic on mask")
+		}
+	}()
+	MakeStacked(MakeTemporalConvolution(2, 4, 2, 1)).ForwardMasked(G, x, mask)
+}
+
+func TestStackedMaskedPerStepLayer(t *testing.T) {
+	s := MakeStacked(MakeGRU(4, 5, 3), MakeHighway(3, "Tanh"), MakeLSTM(3, 5, 2))
+	x := []*Matrix{RandMat(4, 1), RandMat(4, 1), RandMat(4, 1), Mat(4, 1)}
+	G := &Graph{NeedsBackprop: true}
+	padded := s.ForwardMasked(G, x, []bool{true, true, true, false})
+	unpadded := s.ForwardSequence(G, x[:3])
+	if len(padded) != 4 || padded[3] != nil {
+		t.Fatal("padding step must have no output")
+	}
+	for i := range unpadded {
+		sameMatrix(t, padded[i], unpadded[i], "padded output")
+	}
+	for i := range padded[2].DW {
+		padded[2].DW[i] = 1
+	}
+	G.Backward()
+}
+
+func TestStackedBundle(t *testing.T) {
+	layer := func(name string, input int) string {
+		return `{"Name": "` + name + `", "Architecture": "BiRNN", "Config": {"Merge": 2,
+			"Forward": {"Architecture": "OutputlessGRU", "Config": {"Input": ` + strconv.Itoa(input) + `, "Hidden": 5}},
+			"Backward": {"Architecture": "OutputlessGRU", "Config": {"Input": ` + strconv.Itoa(input) + `, "Hidden": 5}}}}`
+	}
+	config := json.RawMessage(`{"Residual": true, "Layers": [` + layer("first", 3) + `,` + layer("second", 5) + `]}`)
+	b, e := NewBundle("Stacked", config)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(b.Parameters) != 2*2*9 || b.Parameters["Stacked_layer1_backward_Uh"] == nil {
+		t.Fatalf("wrong parameters %d", len(b.Parameters))
+	}
+	var buffer bytes.Buffer
+	if e = WriteBundle(&buffer, b); e != nil {
+		t.Fatal(e)
+	}
+	loaded, e := ReadBundle(&buffer)
+	if e != nil {
+		t.Fatal(e)
+	}
+	G := &Graph{}
+	x := []*Matrix{RandMat(3, 1), RandMat(3, 1)}
+	sameMatrix(t, loaded.Model.(*Stacked).ForwardSequence(G, x)[1], b.Model.(*Stacked).ForwardSequence(G, x)[1], "loaded output")
+}
@@ -1,7 +1,5 @@
 package gortex
 
-import "fmt"
-
 type RNN struct {
 	Wxh  *Matrix
 	Whh  *Matrix
@@ -9,12 +7,13 @@ type RNN struct {
 	Bias *Matrix
 }
 
-func MakeRNN(x_size, h_size, out_size int) *RNN {
+func MakeRNN(x_size, h_size, out_size int, options ...InitOption) *RNN {
 	net := new(RNN)
-	net.Wxh = RandXavierMat(h_size, x_size)
-	net.Whh = RandXavierMat(h_size, h_size)
-	net.Who = RandXavierMat(out_size, h_size)
-	net.Bias = RandXavierMat(h_size, 1)
+	in := xavier(options)
+	net.Wxh = in.weights(h_size, x_size)
+	net.Whh = in.recurrent(h_size, h_size)
+	net.Who = in.weights(out_size, h_size)
+	net.Bias = in.biases(h_size)
 	return net
 }
 
@@ -25,16 +24,7 @@ func (rnn *RNN) GetParameters(namespace string) map[string]*Matrix {
 }
 
 func (rnn *RNN) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			v.W = m.W
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *RNN) Step(g *Graph, x, h_prev *Matrix) (h, y *Matrix) {
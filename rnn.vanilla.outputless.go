@@ -1,18 +1,17 @@
 package gortex
 
-import "fmt"
-
 type OutputlessRNN struct {
 	Wxh  *Matrix
 	Whh  *Matrix
 	Bias *Matrix
 }
 
-func MakeOutputlessRNN(x_size, h_size int) *OutputlessRNN {
+func MakeOutputlessRNN(x_size, h_size int, options ...InitOption) *OutputlessRNN {
 	net := new(OutputlessRNN)
-	net.Wxh = RandXavierMat(h_size, x_size)
-	net.Whh = RandXavierMat(h_size, h_size)
-	net.Bias = RandXavierMat(h_size, 1)
+	in := xavier(options)
+	net.Wxh = in.weights(h_size, x_size)
+	net.Whh = in.recurrent(h_size, h_size)
+	net.Bias = in.biases(h_size)
 	return net
 }
 
@@ -23,16 +22,7 @@ func (rnn *OutputlessRNN) GetParameters(namespace string) map[string]*Matrix {
 }
 
 func (rnn *OutputlessRNN) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range rnn.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			v.W = m.W
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(rnn.GetParameters(namespace), parameters)
 }
 
 func (rnn *OutputlessRNN) Step(g *Graph, x, h_prev *Matrix) (h *Matrix) {
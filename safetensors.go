@@ -0,0 +1,418 @@
+package gortex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// safetensors format https://github.com/huggingface/safetensors
+// 8 bytes little endian header size, json header, raw little endian tensor data
+
+const safetensorsLookupTables = "gortex_lookup_tables"
+
+type safetensorsEntry struct {
+	DType       string  `json:"dtype"`
+	Shape       []int   `json:"shape"`
+	DataOffsets []int64 `json:"data_offsets"`
+}
+
+// NameRule maps tensor written by other framework onto gortex parameters
+type NameRule struct {
+	Pattern     string   // regular expression matched against tensor name
+	Names       []string // gortex names, $1 refers to pattern groups, {i} is replaced by split part index
+	Split       int      // split tensor along first axis into parts, defaults to len(Names)
+	Transpose   bool     // transpose 2d tensor before split
+	Accumulate  bool     // add to already loaded parameter, e.g. pytorch bias_ih and bias_hh
+	LookupTable bool     // [vocab, embedding] tensor becomes column major lookup table
+	re          *regexp.Regexp
+}
+
+// TorchLSTMRules maps pytorch nn.LSTM layer weights (gate order i, f, g, o) onto LSTM or OutputlessLSTM parameters
+func TorchLSTMRules(namespace string, layer int) []NameRule {
+	gates := func(kind string) []string {
+		return []string{namespace + "_" + kind + "i", namespace + "_" + kind + "f", namespace + "_" + kind + "c", namespace + "_" + kind + "o"}
+	}
+	suffix := fmt.Sprintf("_l%d$", layer)
+	return []NameRule{
+		{Pattern: `(^|\.)weight_ih` + suffix, Names: gates("W")},
+		{Pattern: `(^|\.)weight_hh` + suffix, Names: gates("U")},
+		{Pattern: `(^|\.)bias_ih` + suffix, Names: gates("B"), Accumulate: true},
+		{Pattern: `(^|\.)bias_hh` + suffix, Names: gates("B"), Accumulate: true},
+	}
+}
+
+// KerasGRURules maps keras GRU (reset_after=False, gate order z, r, h) weights found under prefix
+// onto GRU or OutputlessGRU parameters
+func KerasGRURules(namespace, prefix string) []NameRule {
+	gates := func(kind string) []string {
+		return []string{namespace + "_" + kind + "z", namespace + "_" + kind + "r", namespace + "_" + kind + "h"}
+	}
+	prefix = regexp.QuoteMeta(prefix)
+	return []NameRule{
+		{Pattern: `^` + prefix + `.*/kernel(:0)?$`, Names: gates("W"), Transpose: true},
+		{Pattern: `^` + prefix + `.*/recurrent_kernel(:0)?$`, Names: gates("U"), Transpose: true},
+		{Pattern: `^` + prefix + `.*/bias(:0)?$`, Names: gates("B")},
+	}
+}
+
+// TorchDilatedConvolutionRules maps pytorch layers found as conv.<layer> (nn.Conv1d with kernel_size=3,
+// dilation=padding=2^layer) and gate.<layer> (nn.Linear) onto DilatedTemporalConvolution parameters,
+// conv weight [out, in, 3] is split into out kernels of in x 3, empty gate means model without gates
+func TorchDilatedConvolutionRules(namespace, conv, gate string, kernelSizes []int) []NameRule {
+	var rules []NameRule
+	for layer, n := range kernelSizes {
+		prefix := fmt.Sprintf("%s_layer%d_", namespace, layer)
+		module := func(name string) string {
+			return fmt.Sprintf(`(^|\.)%s\.%d\.`, regexp.QuoteMeta(name), layer)
+		}
+		rules = append(rules,
+			NameRule{Pattern: module(conv) + `weight$`, Names: []string{prefix + "kernel{i}"}, Split: n},
+			NameRule{Pattern: module(conv) + `bias$`, Names: []string{prefix + "conv_bias"}},
+		)
+		if len(gate) > 0 {
+			rules = append(rules,
+				NameRule{Pattern: module(gate) + `weight$`, Names: []string{prefix + "wo"}},
+				NameRule{Pattern: module(gate) + `bias$`, Names: []string{prefix + "bias"}},
+			)
+		}
+	}
+	return rules
+}
+
+// tensor is n dimensional float32 array read from file
+type tensor struct {
+	shape []int
+	data  []float32
+}
+
+// matrix collapses tensor into 2d matrix, leading unit dimensions are dropped
+func (t *tensor) matrix() *Matrix {
+	shape := t.shape
+	for len(shape) > 2 && shape[0] == 1 {
+		shape = shape[1:]
+	}
+	switch len(shape) {
+	case 0:
+		return &Matrix{Rows: 1, Columns: 1, W: t.data}
+	case 1:
+		return &Matrix{Rows: shape[0], Columns: 1, W: t.data}
+	}
+	rows := 1
+	for _, d := range shape[:len(shape)-1] {
+		rows *= d
+	}
+	return &Matrix{Rows: rows, Columns: shape[len(shape)-1], W: t.data}
+}
+
+// target expands template into gortex parameter name
+func (rule *NameRule) target(name, template string) string {
+	match := rule.re.FindStringSubmatchIndex(name)
+	return string(rule.re.ExpandString(nil, template, name, match))
+}
+
+func lookupTable(m *Matrix) *Matrix {
+	// [vocab, embedding] row major is the same memory as column major embedding x vocab
+	m.Rows, m.Columns = m.Columns, m.Rows
+	m.markLookupTable()
+	return m
+}
+
+func (rule *NameRule) apply(name string, t *tensor, model map[string]*Matrix) error {
+	if rule.LookupTable {
+		m := t.matrix()
+		if m.Columns == 1 {
+			return fmt.Errorf("tensor %s of shape %v can not be lookup table", name, t.shape)
+		}
+		model[rule.target(name, rule.Names[0])] = lookupTable(m)
+		return nil
+	}
+	if rule.Transpose {
+		m := t.matrix()
+		if len(t.shape) != 2 {
+			return fmt.Errorf("tensor %s of shape %v can not be transposed", name, t.shape)
+		}
+		data := make([]float32, len(t.data))
+		for r := 0; r < m.Rows; r++ {
+			for c := 0; c < m.Columns; c++ {
+				data[c*m.Rows+r] = t.data[r*m.Columns+c]
+			}
+		}
+		t = &tensor{shape: []int{m.Columns, m.Rows}, data: data}
+	}
+	parts := rule.Split
+	if parts == 0 {
+		parts = len(rule.Names)
+	}
+	if len(t.shape) == 0 || t.shape[0]%parts != 0 {
+		return fmt.Errorf("tensor %s of shape %v can not be split into %d parts", name, t.shape, parts)
+	}
+	if len(rule.Names) != parts && len(rule.Names) != 1 {
+		return fmt.Errorf("rule %s has %d names for %d parts", rule.Pattern, len(rule.Names), parts)
+	}
+	size := len(t.data) / parts
+	shape := append([]int{t.shape[0] / parts}, t.shape[1:]...)
+	for i := 0; i < parts; i++ {
+		template := rule.Names[0]
+		if len(rule.Names) == parts {
+			template = rule.Names[i]
+		}
+		target := strings.Replace(rule.target(name, template), "{i}", strconv.Itoa(i), -1)
+		part := (&tensor{shape: shape, data: t.data[i*size : (i+1)*size]}).matrix()
+		if existing, ok := model[target]; ok && rule.Accumulate {
+			if existing.Rows != part.Rows || existing.Columns != part.Columns {
+				return fmt.Errorf("can not accumulate %s of %dx%d into %s of %dx%d", name, part.Rows, part.Columns, target, existing.Rows, existing.Columns)
+			}
+			for j := range part.W {
+				existing.W[j] += part.W[j]
+			}
+			continue
+		}
+		model[target] = part
+	}
+	return nil
+}
+
+// elements checks dtype, shape and data offsets of header entry before anything is allocated
+// and returns number of tensor elements
+func (entry *safetensorsEntry) elements(name string) (int, error) {
+	var size int
+	switch entry.DType {
+	case "F32":
+		size = 4
+	case "F64":
+		size = 8
+	case "F16", "BF16":
+		size = 2
+	default:
+		return 0, fmt.Errorf("tensor %s has unsupported dtype %s", name, entry.DType)
+	}
+	n := 1
+	for _, d := range entry.Shape {
+		if d < 0 {
+			return 0, fmt.Errorf("tensor %s has negative dimension in shape %v", name, entry.Shape)
+		}
+		if d > 0 && n > maxInt/size/d {
+			return 0, fmt.Errorf("tensor %s of shape %v is too large", name, entry.Shape)
+		}
+		n *= d
+	}
+	if len(entry.DataOffsets) != 2 || entry.DataOffsets[0] < 0 || entry.DataOffsets[1]-entry.DataOffsets[0] != int64(n*size) {
+		return 0, fmt.Errorf("tensor %s of shape %v %s has wrong data offsets %v", name, entry.Shape, entry.DType, entry.DataOffsets)
+	}
+	return n, nil
+}
+
+// decodeTensor converts n elements of entry dtype, b length is checked by elements
+func decodeTensor(entry *safetensorsEntry, n int, b []byte) *tensor {
+	data := make([]float32, n)
+	le := binary.LittleEndian
+	switch entry.DType {
+	case "F32":
+		for i := range data {
+			data[i] = math.Float32frombits(le.Uint32(b[4*i:]))
+		}
+	case "F64":
+		for i := range data {
+			data[i] = float32(math.Float64frombits(le.Uint64(b[8*i:])))
+		}
+	case "F16", "BF16":
+		half := make([]uint16, n)
+		for i := range half {
+			half[i] = le.Uint16(b[2*i:])
+		}
+		if entry.DType == "F16" {
+			widen(data, half, Float16)
+		} else {
+			widen(data, half, BFloat16)
+		}
+	}
+	return &tensor{shape: entry.Shape, data: data}
+}
+
+// ReadSafetensors reads tensors applying first matching rule to every tensor name,
+// tensors matching no rule keep their names
+func ReadSafetensors(r io.Reader, rules ...NameRule) (map[string]*Matrix, error) {
+	for i := range rules {
+		re, e := regexp.Compile(rules[i].Pattern)
+		if e != nil {
+			return nil, e
+		}
+		rules[i].re = re
+	}
+	var size [8]byte
+	if _, e := io.ReadFull(r, size[:]); e != nil {
+		return nil, e
+	}
+	headerSize := binary.LittleEndian.Uint64(size[:])
+	if headerSize > 100<<20 {
+		return nil, fmt.Errorf("safetensors header of %d bytes is too large", headerSize)
+	}
+	header := make([]byte, headerSize)
+	if _, e := io.ReadFull(r, header); e != nil {
+		return nil, e
+	}
+	var raw map[string]json.RawMessage
+	if e := json.Unmarshal(header, &raw); e != nil {
+		return nil, e
+	}
+	lookupTables := make(map[string]bool)
+	if metadata, ok := raw["__metadata__"]; ok {
+		var m map[string]string
+		if e := json.Unmarshal(metadata, &m); e != nil {
+			return nil, e
+		}
+		for _, name := range strings.Split(m[safetensorsLookupTables], ",") {
+			lookupTables[name] = true
+		}
+		delete(raw, "__metadata__")
+	}
+	entries := make(map[string]*safetensorsEntry, len(raw))
+	names := make([]string, 0, len(raw))
+	for name, message := range raw {
+		entry := new(safetensorsEntry)
+		if e := json.Unmarshal(message, entry); e != nil {
+			return nil, fmt.Errorf("tensor %s: %v", name, e)
+		}
+		if _, e := entry.elements(name); e != nil {
+			return nil, e
+		}
+		entries[name] = entry
+		names = append(names, name)
+	}
+	// read data sequentially
+	sort.Slice(names, func(i, j int) bool { return entries[names[i]].DataOffsets[0] < entries[names[j]].DataOffsets[0] })
+	model := make(map[string]*Matrix, len(names))
+	var position int64
+	for _, name := range names {
+		entry := entries[name]
+		if entry.DataOffsets[0] < position {
+			return nil, fmt.Errorf("tensor %s overlaps previous tensor", name)
+		}
+		if _, e := io.CopyN(ioutil.Discard, r, entry.DataOffsets[0]-position); e != nil {
+			return nil, e
+		}
+		// memory grows with data actually read so broken offsets can not exhaust it
+		span := entry.DataOffsets[1] - entry.DataOffsets[0]
+		b, e := ioutil.ReadAll(io.LimitReader(r, span))
+		if e != nil {
+			return nil, fmt.Errorf("tensor %s: %v", name, e)
+		}
+		if int64(len(b)) != span {
+			return nil, fmt.Errorf("tensor %s: %v", name, io.ErrUnexpectedEOF)
+		}
+		position = entry.DataOffsets[1]
+		n, _ := entry.elements(name)
+		t := decodeTensor(entry, n, b)
+		applied := false
+		for i := range rules {
+			if rules[i].re.MatchString(name) {
+				if e = rules[i].apply(name, t, model); e != nil {
+					return nil, e
+				}
+				applied = true
+				break
+			}
+		}
+		if !applied {
+			m := t.matrix()
+			if lookupTables[name] {
+				m = lookupTable(m)
+			}
+			model[name] = m
+		}
+	}
+	return model, nil
+}
+
+// WriteSafetensors writes model as float32 tensors, vectors get 1d shape,
+// lookup tables are written as [vocab, embedding] and listed in metadata
+func WriteSafetensors(w io.Writer, m map[string]*Matrix, metadata map[string]string) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	header := make(map[string]interface{}, len(m)+1)
+	var lookupTables []string
+	var offset int64
+	for _, name := range names {
+		v := m[name]
+		if len(v.W) != v.Rows*v.Columns {
+			return fmt.Errorf("parameter %s has %d weights but shape %dx%d", name, len(v.W), v.Rows, v.Columns)
+		}
+		shape := []int{v.Rows, v.Columns}
+		switch {
+		case v.IsLookupTable():
+			shape = []int{v.Columns, v.Rows}
+			lookupTables = append(lookupTables, name)
+		case v.Columns == 1:
+			shape = []int{v.Rows}
+		}
+		size := int64(4 * len(v.W))
+		header[name] = &safetensorsEntry{DType: "F32", Shape: shape, DataOffsets: []int64{offset, offset + size}}
+		offset += size
+	}
+	meta := make(map[string]string)
+	for k, v := range metadata {
+		meta[k] = v
+	}
+	if len(lookupTables) > 0 {
+		meta[safetensorsLookupTables] = strings.Join(lookupTables, ",")
+	}
+	if len(meta) > 0 {
+		header["__metadata__"] = meta
+	}
+	encoded, e := json.Marshal(header)
+	if e != nil {
+		return e
+	}
+	// pad header with spaces so data starts 8 byte aligned
+	for len(encoded)%8 != 0 {
+		encoded = append(encoded, ' ')
+	}
+	bw := bufio.NewWriter(w)
+	var scratch [8]byte
+	binary.LittleEndian.PutUint64(scratch[:], uint64(len(encoded)))
+	bw.Write(scratch[:])
+	bw.Write(encoded)
+	for _, name := range names {
+		for _, f := range m[name].W {
+			binary.LittleEndian.PutUint32(scratch[:], math.Float32bits(f))
+			bw.Write(scratch[:4])
+		}
+	}
+	return bw.Flush()
+}
+
+// SaveSafetensors saves model to safetensors file
+func SaveSafetensors(name string, m map[string]*Matrix, metadata map[string]string) error {
+	f, e := os.Create(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	if e = WriteSafetensors(f, m, metadata); e != nil {
+		return e
+	}
+	return f.Close()
+}
+
+// LoadSafetensors loads model from safetensors file, see ReadSafetensors
+func LoadSafetensors(name string, rules ...NameRule) (map[string]*Matrix, error) {
+	f, e := os.Open(name)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	return ReadSafetensors(bufio.NewReader(f), rules...)
+}
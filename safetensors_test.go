@@ -0,0 +1,170 @@
+package gortex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestSafetensors(t *testing.T) {
+	embedding := RandMat(4, 10)
+	embedding.Touch(0)
+	model := map[string]*Matrix{
+		"Encoder_Wf": RandMat(7, 5),
+		"Encoder_Bf": RandMat(7, 1),
+		"Embedding":  embedding,
+	}
+	name := filepath.Join(t.TempDir(), "model.safetensors")
+	if e := SaveSafetensors(name, model, map[string]string{"format": "pt"}); e != nil {
+		t.Fatal(e)
+	}
+	loaded, e := LoadSafetensors(name)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(loaded) != len(model) {
+		t.Fatalf("loaded %d parameters but %d saved", len(loaded), len(model))
+	}
+	for k, v := range model {
+		l := loaded[k]
+		if l.Rows != v.Rows || l.Columns != v.Columns || l.IsLookupTable() != v.IsLookupTable() {
+			t.Fatalf("parameter %s loaded as %dx%d", k, l.Rows, l.Columns)
+		}
+		for i := range v.W {
+			if l.W[i] != v.W[i] {
+				t.Fatalf("parameter %s weight %d mismatch", k, i)
+			}
+		}
+	}
+}
+
+func TestSafetensorsTorchLSTM(t *testing.T) {
+	hidden, input := 3, 2
+	// pytorch layout, gates stacked along rows in i, f, g, o order
+	torch := map[string]*Matrix{
+		"lstm.weight_ih_l0": RandMat(4*hidden, input),
+		"lstm.weight_hh_l0": RandMat(4*hidden, hidden),
+		"lstm.bias_ih_l0":   RandMat(4*hidden, 1),
+		"lstm.bias_hh_l0":   RandMat(4*hidden, 1),
+	}
+	var buffer bytes.Buffer
+	if e := WriteSafetensors(&buffer, torch, nil); e != nil {
+		t.Fatal(e)
+	}
+	loaded, e := ReadSafetensors(&buffer, TorchLSTMRules("Encoder", 0)...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	lstm := MakeOutputlessLSTM(input, hidden)
+	if e = lstm.SetParameters("Encoder", loaded); e != nil {
+		t.Fatal(e)
+	}
+	for gate, suffix := range []string{"i", "f", "c", "o"} {
+		w := loaded["Encoder_W"+suffix]
+		if w.Rows != hidden || w.Columns != input {
+			t.Fatalf("Encoder_W%s has shape %dx%d", suffix, w.Rows, w.Columns)
+		}
+		for j := range w.W {
+			if w.W[j] != torch["lstm.weight_ih_l0"].W[gate*hidden*input+j] {
+				t.Fatalf("Encoder_W%s weight %d mismatch", suffix, j)
+			}
+		}
+		b := loaded["Encoder_B"+suffix]
+		for j := range b.W {
+			expected := torch["lstm.bias_ih_l0"].W[gate*hidden+j] + torch["lstm.bias_hh_l0"].W[gate*hidden+j]
+			if b.W[j] != expected {
+				t.Fatalf("Encoder_B%s bias %d is %f but %f expected", suffix, j, b.W[j], expected)
+			}
+		}
+	}
+}
+
+// writeTensors writes n dimensional float32 tensors as other frameworks do
+func writeTensors(t *testing.T, tensors map[string]*tensor) *bytes.Buffer {
+	header := make(map[string]*safetensorsEntry)
+	var data bytes.Buffer
+	for name, v := range tensors {
+		offset := int64(data.Len())
+		for _, f := range v.data {
+			binary.Write(&data, binary.LittleEndian, f)
+		}
+		header[name] = &safetensorsEntry{DType: "F32", Shape: v.shape, DataOffsets: []int64{offset, int64(data.Len())}}
+	}
+	encoded, e := json.Marshal(header)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, uint64(len(encoded)))
+	buffer.Write(encoded)
+	buffer.Write(data.Bytes())
+	return &buffer
+}
+
+func TestSafetensorsTorchDilatedConvolution(t *testing.T) {
+	input, kernelSizes := 2, []int{3, 4}
+	dtc := MakeDilatedTemporalConvolution(input, kernelSizes, true)
+	// pytorch Conv1d weight is [out, in, kernel], kernel position k looks at t+(k-1)*dilation
+	// as receptive field of layer does
+	torch := make(map[string]*tensor)
+	in := input
+	for layer, n := range kernelSizes {
+		weight := &tensor{shape: []int{n, in, 3}}
+		for o := 0; o < n; o++ {
+			for i := 0; i < in; i++ {
+				for k := 0; k < 3; k++ {
+					weight.data = append(weight.data, dtc.Kernels[layer][o].W[i*3+k])
+				}
+			}
+		}
+		torch[fmt.Sprintf("encoder.convs.%d.weight", layer)] = weight
+		torch[fmt.Sprintf("encoder.convs.%d.bias", layer)] = &tensor{shape: []int{n}, data: dtc.ConvBiases[layer].W}
+		torch[fmt.Sprintf("encoder.gates.%d.weight", layer)] = &tensor{shape: []int{n, n}, data: dtc.Gates[layer].W}
+		torch[fmt.Sprintf("encoder.gates.%d.bias", layer)] = &tensor{shape: []int{n}, data: dtc.Biases[layer].W}
+		in = n
+	}
+	loaded, e := ReadSafetensors(writeTensors(t, torch), TorchDilatedConvolutionRules("DTC", "convs", "gates", kernelSizes)...)
+	if e != nil {
+		t.Fatal(e)
+	}
+	restored := MakeDilatedTemporalConvolution(input, kernelSizes, true)
+	if e = restored.SetParameters("DTC", loaded); e != nil {
+		t.Fatal(e)
+	}
+	if e = SetParameters(restored.GetParameters("DTC"), loaded); e != nil {
+		t.Fatal(e)
+	}
+	expected, actual := dtc.GetParameters("DTC"), restored.GetParameters("DTC")
+	for k, v := range expected {
+		for i := range v.W {
+			if actual[k].W[i] != v.W[i] {
+				t.Fatalf("parameter %s weight %d mismatch", k, i)
+			}
+		}
+	}
+}
+
+func TestSafetensorsBrokenHeader(t *testing.T) {
+	for _, header := range []string{
+		`{"W":{"dtype":"F32","shape":[-1],"data_offsets":[0,4]}}`,
+		`{"W":{"dtype":"F32","shape":[4611686018427387904,4],"data_offsets":[0,0]}}`,
+		`{"W":{"dtype":"F32","shape":[2],"data_offsets":[0,4]}}`,
+		`{"W":{"dtype":"F32","shape":[1],"data_offsets":[-4,0]}}`,
+		`{"W":{"dtype":"I8","shape":[4],"data_offsets":[0,4]}}`,
+		// offsets agree with shape but data is missing
+		`{"W":{"dtype":"F32","shape":[1000000000],"data_offsets":[0,4000000000]}}`,
+	} {
+		var buffer bytes.Buffer
+		binary.Write(&buffer, binary.LittleEndian, uint64(len(header)))
+		buffer.WriteString(header)
+		buffer.Write(make([]byte, 4))
+		if _, e := ReadSafetensors(&buffer); e == nil {
+			t.Fatalf("broken header %s must be reported", header)
+		} else {
+			t.Log(e)
+		}
+	}
+}
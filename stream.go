@@ -0,0 +1,158 @@
+package gortex
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+)
+
+// GzipExtension makes files created by Save functions gzip compressed,
+// compressed files are recognized on load by magic bytes whatever name they have
+const GzipExtension = ".gz"
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns buffered reader of r which is transparently decompressed if r is gzip stream
+func decompress(r io.Reader) (*bufio.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, e := br.Peek(len(gzipMagic)); e == nil && magic[0] == gzipMagic[0] && magic[1] == gzipMagic[1] {
+		gz, e := gzip.NewReader(br)
+		if e != nil {
+			return nil, e
+		}
+		return bufio.NewReader(gz), nil
+	}
+	return br, nil
+}
+
+// fileWriter is buffered file writer, compressed if file name has GzipExtension
+type fileWriter struct {
+	*bufio.Writer
+	gz *gzip.Writer
+	f  *os.File
+}
+
+func createFile(name string) (*fileWriter, error) {
+	f, e := os.Create(name)
+	if e != nil {
+		return nil, e
+	}
+	fw := &fileWriter{f: f}
+	if strings.HasSuffix(name, GzipExtension) {
+		fw.gz = gzip.NewWriter(f)
+		fw.Writer = bufio.NewWriter(fw.gz)
+	} else {
+		fw.Writer = bufio.NewWriter(f)
+	}
+	return fw, nil
+}
+
+// Close flushes everything written and closes file, first error is returned
+func (fw *fileWriter) Close() error {
+	e := fw.Flush()
+	if fw.gz != nil {
+		if ge := fw.gz.Close(); e == nil {
+			e = ge
+		}
+	}
+	if fe := fw.f.Close(); e == nil {
+		e = fe
+	}
+	return e
+}
+
+// saveFile writes file with write, file is removed if anything fails
+func saveFile(name string, write func(w io.Writer) error) error {
+	fw, e := createFile(name)
+	if e != nil {
+		return e
+	}
+	if e = write(fw); e != nil {
+		fw.Close()
+		os.Remove(name)
+		return e
+	}
+	if e = fw.Close(); e != nil {
+		os.Remove(name)
+	}
+	return e
+}
+
+// loadFile reads possibly compressed file with read
+func loadFile(name string, read func(r *bufio.Reader) error) error {
+	f, e := os.Open(name)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+	r, e := decompress(f)
+	if e != nil {
+		return e
+	}
+	return read(r)
+}
+
+// ReadModel reads model in binary or legacy json format, gzip compressed or not
+func ReadModel(r io.Reader) (map[string]*Matrix, error) {
+	br, e := decompress(r)
+	if e != nil {
+		return nil, e
+	}
+	if magic, e := br.Peek(len(ModelMagic)); e == nil && string(magic) == ModelMagic {
+		return ReadModelBinary(br)
+	}
+	var m map[string]*Matrix
+	if e = json.NewDecoder(br).Decode(&m); e != nil {
+		return nil, e
+	}
+	return m, nil
+}
+
+// WriteModelJSON writes model in legacy json format
+func WriteModelJSON(w io.Writer, m map[string]*Matrix) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// Write writes dictionary as json
+func (d *Dictionary) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(d)
+}
+
+// Read reads dictionary written by Write, gzip compressed or not
+func (d *Dictionary) Read(r io.Reader) error {
+	br, e := decompress(r)
+	if e != nil {
+		return e
+	}
+	return json.NewDecoder(br).Decode(d)
+}
+
+// ReadDictionary reads dictionary written by Dictionary.Write, gzip compressed or not
+func ReadDictionary(r io.Reader) (*Dictionary, error) {
+	d := new(Dictionary)
+	if e := d.Read(r); e != nil {
+		return nil, e
+	}
+	return d, nil
+}
+
+// Write writes bidictionary as json
+func (d *BiDictionary) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(d)
+}
+
+// ReadBiDictionary reads bidictionary written by BiDictionary.Write, gzip compressed or not
+func ReadBiDictionary(r io.Reader) (*BiDictionary, error) {
+	br, e := decompress(r)
+	if e != nil {
+		return nil, e
+	}
+	d := new(BiDictionary)
+	if e = json.NewDecoder(br).Decode(d); e != nil {
+		return nil, e
+	}
+	return d, nil
+}
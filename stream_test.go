@@ -0,0 +1,71 @@
+package gortex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGzipStreams(t *testing.T) {
+	dir := t.TempDir()
+	model := map[string]*Matrix{"Encoder_Wf": RandMat(3, 4), "Encoder_Bf": RandMat(3, 1)}
+	dic := NewDictionary()
+	dic.Add("hello")
+	bidic := &BiDictionary{First: dic, Second: NewDictionary()}
+	for _, name := range []string{"model", "model" + GzipExtension} {
+		name = filepath.Join(dir, name)
+		for _, save := range []func(string, map[string]*Matrix) error{SaveModel, SaveModelJSON} {
+			if e := save(name, model); e != nil {
+				t.Fatal(e)
+			}
+			b, _ := os.ReadFile(name)
+			if compressed := bytes.HasPrefix(b, gzipMagic); compressed != (filepath.Ext(name) == GzipExtension) {
+				t.Fatalf("%s is compressed: %v", name, compressed)
+			}
+			loaded, e := LoadModel(name)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if loaded["Encoder_Wf"].W[5] != model["Encoder_Wf"].W[5] {
+				t.Fatalf("%s: model mismatch", name)
+			}
+		}
+		if e := dic.Save(name); e != nil {
+			t.Fatal(e)
+		}
+		loaded, e := LoadDictionary(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if loaded.IDByToken("hello") != dic.IDByToken("hello") {
+			t.Fatalf("%s: dictionary mismatch", name)
+		}
+		if e = bidic.Save(name); e != nil {
+			t.Fatal(e)
+		}
+		loadedBi, e := bidic.Load(name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if loadedBi.First.IDByToken("hello") != dic.IDByToken("hello") || loadedBi.Second.Len() != 3 {
+			t.Fatalf("%s: bidictionary mismatch", name)
+		}
+	}
+
+	// any gzip compressed stream is accepted by readers
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	if e := WriteModelBinary(gz, model); e != nil {
+		t.Fatal(e)
+	}
+	gz.Close()
+	loaded, e := ReadModel(&buffer)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if loaded["Encoder_Bf"].W[2] != model["Encoder_Bf"].W[2] {
+		t.Fatal("model read from gzip stream mismatch")
+	}
+}
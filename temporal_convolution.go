@@ -15,20 +15,21 @@ type TemporalConvolution struct {
 	KernelShift int
 }
 
-func MakeTemporalConvolution(kernels, input_size, kernel_size, kernel_shift int) *TemporalConvolution {
+func MakeTemporalConvolution(kernels, input_size, kernel_size, kernel_shift int, options ...InitOption) *TemporalConvolution {
 	tcv := new(TemporalConvolution)
+	in := xavier(options)
 	tcv.KernelSize = kernel_size
 	tcv.KernelShift = kernel_shift
 	tcv.Kernels = make([]*Matrix, kernels)
-	tcv.Biases = RandXavierMat(kernels, 1)
+	tcv.Biases = in.biases(kernels)
 	tcv.Pads = make([]*Matrix, kernel_size-1)
 
 	for i := range tcv.Kernels {
-		tcv.Kernels[i] = RandXavierMat(input_size, kernel_size)
+		tcv.Kernels[i] = in.weights(input_size, kernel_size)
 	}
 
 	for i := range tcv.Pads {
-		tcv.Pads[i] = RandXavierMat(input_size, 1)
+		tcv.Pads[i] = in.biases(input_size)
 	}
 	return tcv
 }
@@ -47,16 +48,7 @@ func (tcv *TemporalConvolution) GetParameters(namespace string) map[string]*Matr
 }
 
 func (tcv *TemporalConvolution) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range tcv.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(tcv.GetParameters(namespace), parameters)
 }
 
 func (tcv *TemporalConvolution) Step(g *Graph, x []*Matrix) (y []*Matrix) {
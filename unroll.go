@@ -0,0 +1,61 @@
+package gortex
+
+import "fmt"
+
+type UnrollOp struct {
+	Reverse  bool   // run from last step to first, outputs and states keep input order
+	Mask     []bool // false marks padding step, state passes it unchanged and output is nil, nil mask means all steps are valid
+	LastOnly bool   // keep only state and output of last valid step
+}
+
+type UnrollRet struct {
+	Outputs []*Matrix // output of every step, nil when LastOnly
+	States  []*State  // state after every step, nil when LastOnly
+	Last    *State    // state after last valid step, initial state if there were none
+	Output  *Matrix   // output of last valid step
+}
+
+// Unroll runs cell over sequence x starting with initial state, cell.InitialState() if nil,
+// last valid step is the first one in Reverse direction
+func Unroll(g *Graph, cell RecurrentCell, initial *State, x []*Matrix, op *UnrollOp) *UnrollRet {
+	return unroll(g, cell, initial, len(x), func(t int) *Matrix { return x[t] }, op)
+}
+
+// UnrollTokens runs cell over embeddings of tokens from column major lookup table,
+// tokens of padding steps are not looked up
+func UnrollTokens(g *Graph, cell RecurrentCell, initial *State, lookupTable *Matrix, tokens []uint, op *UnrollOp) *UnrollRet {
+	return unroll(g, cell, initial, len(tokens), func(t int) *Matrix { return g.Lookup(lookupTable, int(tokens[t])) }, op)
+}
+
+func unroll(g *Graph, cell RecurrentCell, initial *State, length int, input func(t int) *Matrix, op *UnrollOp) *UnrollRet {
+	if op == nil {
+		op = new(UnrollOp)
+	}
+	if op.Mask != nil && len(op.Mask) != length {
+		panic(fmt.Errorf("mask of %d steps for sequence of %d steps", len(op.Mask), length))
+	}
+	if initial == nil {
+		initial = cell.InitialState()
+	}
+	ret := &UnrollRet{Last: initial}
+	if !op.LastOnly {
+		ret.Outputs = make([]*Matrix, length)
+		ret.States = make([]*State, length)
+	}
+	for i := 0; i < length; i++ {
+		t := i
+		if op.Reverse {
+			t = length - 1 - i
+		}
+		if op.Mask == nil || op.Mask[t] {
+			ret.Last, ret.Output = cell.StepState(g, input(t), ret.Last)
+			if !op.LastOnly {
+				ret.Outputs[t] = ret.Output
+			}
+		}
+		if !op.LastOnly {
+			ret.States[t] = ret.Last
+		}
+	}
+	return ret
+}
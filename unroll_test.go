@@ -0,0 +1,75 @@
+package gortex
+
+import "testing"
+
+func sameMatrix(t *testing.T, a, b *Matrix, message string) {
+	if len(a.W) != len(b.W) {
+		t.Fatalf("%s: sizes %d and %d differ", message, len(a.W), len(b.W))
+	}
+	for i := range a.W {
+		if a.W[i] != b.W[i] {
+			t.Fatalf("%s: element %d differs %f != %f", message, i, a.W[i], b.W[i])
+		}
+	}
+}
+
+func TestUnroll(t *testing.T) {
+	lstm := MakeLSTM(4, 6, 3)
+	cell, _ := AsRecurrentCell(lstm)
+	x := []*Matrix{RandMat(4, 1), RandMat(4, 1), RandMat(4, 1), RandMat(4, 1)}
+	G := &Graph{}
+
+	// same as hand written loop
+	ret := Unroll(G, cell, nil, x, nil)
+	h, c := Mat(6, 1), Mat(6, 1)
+	for i := range x {
+		var y *Matrix
+		h, c, y = lstm.Step(G, x[i], h, c)
+		sameMatrix(t, ret.Outputs[i], y, "output")
+		sameMatrix(t, ret.States[i].H, h, "state")
+	}
+	sameMatrix(t, ret.Last.C, c, "last state")
+	sameMatrix(t, ret.Output, ret.Outputs[3], "last output")
+
+	// reverse direction is forward run over reversed sequence
+	reversed := Unroll(G, cell, nil, x, &UnrollOp{Reverse: true})
+	backwards := Unroll(G, cell, nil, []*Matrix{x[3], x[2], x[1], x[0]}, nil)
+	for i := range x {
+		sameMatrix(t, reversed.Outputs[i], backwards.Outputs[3-i], "reversed output")
+	}
+	sameMatrix(t, reversed.Last.H, backwards.Last.H, "reversed last state")
+
+	// padding steps do not change state
+	padded := []*Matrix{x[0], x[1], Mat(4, 1), x[2], Mat(4, 1)}
+	masked := Unroll(G, cell, nil, padded, &UnrollOp{Mask: []bool{true, true, false, true, false}})
+	unpadded := Unroll(G, cell, nil, x[:3], nil)
+	if masked.Outputs[2] != nil || masked.Outputs[4] != nil {
+		t.Fatal("padding steps must have no outputs")
+	}
+	sameMatrix(t, masked.States[2].H, unpadded.States[1].H, "padding state")
+	sameMatrix(t, masked.Last.H, unpadded.Last.H, "last valid state")
+	sameMatrix(t, masked.Output, unpadded.Output, "last valid output")
+
+	last := Unroll(G, cell, ret.Last, x, &UnrollOp{LastOnly: true})
+	if last.Outputs != nil || last.States != nil || last.Last.T != 8 {
+		t.Fatal("only last state must be kept and continue from initial one")
+	}
+}
+
+func TestUnrollTokens(t *testing.T) {
+	gru := MakeGRU(4, 5, 7)
+	cell, _ := AsRecurrentCell(gru)
+	embeddings := RandMat(4, 10)
+	G := &Graph{NeedsBackprop: true}
+	// padding token is out of vocabulary, it must not be looked up
+	ret := UnrollTokens(G, cell, nil, embeddings, []uint{3, 9, 1000}, &UnrollOp{Mask: []bool{true, true, false}})
+	expected := Unroll(G, cell, nil, []*Matrix{G.Lookup(embeddings, 3), G.Lookup(embeddings, 9)}, nil)
+	sameMatrix(t, ret.Last.H, expected.Last.H, "token state")
+	if ret.Last.T != 2 {
+		t.Fatalf("made %d steps instead of 2", ret.Last.T)
+	}
+	G.Backward()
+	if !embeddings.IsLookupTable() {
+		t.Fatal("looked up columns must be touched")
+	}
+}
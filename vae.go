@@ -1,8 +1,6 @@
 package gortex
 
 import (
-	"fmt"
-
 	"github.com/vseledkin/gortex/assembler"
 )
 
@@ -27,24 +25,25 @@ type VAE struct {
 	WWB *Matrix
 }
 
-func MakeVae(x_size, z_size int) *VAE {
+func MakeVae(x_size, z_size int, options ...InitOption) *VAE {
 	vae := new(VAE)
+	in := xavier(options)
 	vae.z_size = z_size
 
-	vae.W = RandXavierMat(z_size, x_size)
-	vae.WB = RandXavierMat(z_size, 1)
+	vae.W = in.weights(z_size, x_size)
+	vae.WB = in.biases(z_size)
 
-	vae.W1 = RandXavierMat(z_size, z_size)
-	vae.W1B = RandXavierMat(z_size, 1)
+	vae.W1 = in.weights(z_size, z_size)
+	vae.W1B = in.biases(z_size)
 
-	vae.WW = RandXavierMat(x_size, z_size)
-	vae.WWB = RandXavierMat(x_size, 1)
+	vae.WW = in.weights(x_size, z_size)
+	vae.WWB = in.biases(x_size)
 
-	vae.WW1 = RandXavierMat(x_size, x_size)
-	vae.WW1B = RandXavierMat(x_size, 1)
+	vae.WW1 = in.weights(x_size, x_size)
+	vae.WW1B = in.biases(x_size)
 
-	vae.WM = RandXavierMat(z_size, z_size)
-	vae.WD = RandXavierMat(z_size, z_size)
+	vae.WM = in.weights(z_size, z_size)
+	vae.WD = in.weights(z_size, z_size)
 
 	return vae
 }
@@ -69,16 +68,7 @@ func (vae *VAE) GetParameters(namespace string) map[string]*Matrix {
 }
 
 func (vae *VAE) SetParameters(namespace string, parameters map[string]*Matrix) error {
-	for k, v := range vae.GetParameters(namespace) {
-		fmt.Printf("Look for %s parameters\n", k)
-		if m, ok := parameters[k]; ok {
-			fmt.Printf("Got %s parameters\n", k)
-			copy(v.W, m.W)
-		} else {
-			return fmt.Errorf("Model geometry is not compatible, parameter %s is unknown", k)
-		}
-	}
-	return nil
+	return setParameters(vae.GetParameters(namespace), parameters)
 }
 
 func (vae *VAE) Step(g *Graph, x *Matrix) (sample, mean, logvar *Matrix) {
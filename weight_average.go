@@ -0,0 +1,120 @@
+package gortex
+
+import (
+	"fmt"
+
+	"github.com/vseledkin/gortex/assembler"
+)
+
+// WeightAverage keeps shadow copy of model weights averaged over training
+// either as exponential moving average (Polyak averaging) or as
+// Stochastic Weight Averaging https://arxiv.org/abs/1803.05407
+type WeightAverage struct {
+	Decay   float32 // EMA decay, shadow = Decay*shadow + (1-Decay)*w
+	Cycle   int     // SWA collects weights every Cycle updates, 0 means EMA
+	Start   int     // SWA skips first Start updates, weights of early training are not averaged
+	Updates int     // number of Update calls
+	Samples int     // number of weight snapshots averaged into shadow
+	Shadow  map[string][]float32
+	Counts  map[string]int       // snapshots averaged into every shadow, parameters may appear after averaging started
+	raw     map[string][]float32 // training weights while averaged ones are swapped in
+}
+
+// NewEMA makes exponential moving average of model weights with given decay
+func NewEMA(model map[string]*Matrix, decay float32) *WeightAverage {
+	if decay <= 0 || decay >= 1 {
+		panic(fmt.Errorf("EMA decay must be in (0;1) but %f given", decay))
+	}
+	wa := &WeightAverage{Decay: decay, Shadow: make(map[string][]float32), Counts: make(map[string]int)}
+	wa.init(model)
+	return wa
+}
+
+// NewSWA makes stochastic weight average of model collecting weights at the end of every cycle
+// after start updates, shadow is the first collected snapshot so initial weights are not averaged
+func NewSWA(cycle, start int) *WeightAverage {
+	if cycle < 1 {
+		panic(fmt.Errorf("SWA cycle must be positive but %d given", cycle))
+	}
+	if start < 0 {
+		panic(fmt.Errorf("SWA start must not be negative but %d given", start))
+	}
+	return &WeightAverage{Cycle: cycle, Start: start, Shadow: make(map[string][]float32), Counts: make(map[string]int)}
+}
+
+func (wa *WeightAverage) init(model map[string]*Matrix) {
+	for name, m := range model {
+		wa.Shadow[name] = make([]float32, m.Numel())
+		copy(wa.Shadow[name], m.W)
+		wa.Counts[name] = 1
+	}
+	wa.Samples = 1
+}
+
+// Update accumulates current model weights into shadow ones, call it after Optimizer.Step
+func (wa *WeightAverage) Update(model map[string]*Matrix) {
+	if wa.raw != nil {
+		panic("can not update weight average while averaged weights are swapped in")
+	}
+	wa.Updates++
+	if wa.Cycle > 0 && (wa.Updates <= wa.Start || (wa.Updates-wa.Start)%wa.Cycle != 0) {
+		return
+	}
+	for name, m := range model {
+		shadow, ok := wa.Shadow[name]
+		if !ok {
+			// first snapshot or parameter appeared after averaging started
+			shadow = make([]float32, m.Numel())
+			copy(shadow, m.W)
+			wa.Shadow[name] = shadow
+			wa.Counts[name] = 1
+			continue
+		}
+		if wa.Cycle > 0 {
+			// running mean over snapshots collected since parameter appeared
+			n := float32(wa.Counts[name])
+			assembler.Saxplusbysetz(n/(n+1), shadow, 1/(n+1), m.W, shadow)
+		} else {
+			assembler.Saxplusbysetz(wa.Decay, shadow, 1-wa.Decay, m.W, shadow)
+		}
+		wa.Counts[name]++
+	}
+	wa.Samples++
+}
+
+// SwapIn replaces model weights by averaged ones keeping training weights aside,
+// weights are copied in place so matrices sharing W slices see averaged values too
+func (wa *WeightAverage) SwapIn(model map[string]*Matrix) {
+	if wa.raw != nil {
+		panic("averaged weights are already swapped in")
+	}
+	wa.raw = make(map[string][]float32)
+	for name, m := range model {
+		if shadow, ok := wa.Shadow[name]; ok {
+			raw := make([]float32, m.Numel())
+			copy(raw, m.W)
+			wa.raw[name] = raw
+			copy(m.W, shadow)
+		}
+	}
+}
+
+// SwapOut restores training weights replaced by SwapIn
+func (wa *WeightAverage) SwapOut(model map[string]*Matrix) {
+	if wa.raw == nil {
+		panic("averaged weights are not swapped in")
+	}
+	for name, m := range model {
+		if raw, ok := wa.raw[name]; ok {
+			copy(m.W, raw)
+		}
+	}
+	wa.raw = nil
+}
+
+// SaveModel saves model with averaged weights, training weights stay intact
+func (wa *WeightAverage) SaveModel(name string, model map[string]*Matrix) error {
+	wa.SwapIn(model)
+	defer wa.SwapOut(model)
+	return SaveModel(name, model)
+}
@@ -0,0 +1,52 @@
+package gortex
+
+import "testing"
+
+func TestWeightAverage(t *testing.T) {
+	W := MatFromSlice([][]float32{{0, 0}})
+	model := map[string]*Matrix{"W": W}
+	ema := NewEMA(model, 0.5)
+	swa := NewSWA(2, 0)
+	late := NewSWA(1, 2)
+	if swa.SwapIn(model); W.W[0] != 0 {
+		t.Fatal("SWA without snapshots must keep training weights")
+	}
+	swa.SwapOut(model)
+	for _, v := range []float32{2, 4, 6, 8} {
+		W.W[0], W.W[1] = v, -v
+		ema.Update(model)
+		swa.Update(model)
+		late.Update(model)
+	}
+	// ema: 0 -> 1 -> 2.5 -> 4.25 -> 6.125
+	// swa: mean of snapshots taken after 2-nd and 4-th updates 4, 8
+	// late: mean of snapshots taken after 3-rd and 4-th updates 6, 8
+	for _, c := range []struct {
+		wa       *WeightAverage
+		expected float32
+	}{{ema, 6.125}, {swa, 6}, {late, 7}} {
+		c.wa.SwapIn(model)
+		if Abs(W.W[0]-c.expected) > 1e-6 || Abs(W.W[1]+c.expected) > 1e-6 {
+			t.Fatalf("averaged weights must be %f but %v", c.expected, W.W)
+		}
+		c.wa.SwapOut(model)
+		if W.W[0] != 8 || W.W[1] != -8 {
+			t.Fatalf("training weights must be restored but %v", W.W)
+		}
+	}
+
+	// parameter appearing later is averaged over its own snapshots only
+	swa = NewSWA(1, 0)
+	V := Mat(1, 1)
+	for i, v := range []float32{2, 4, 6, 8} {
+		W.W[0], V.W[0] = v, v
+		if i == 2 {
+			model["V"] = V
+		}
+		swa.Update(model)
+	}
+	if swa.SwapIn(model); W.W[0] != 5 || V.W[0] != 7 {
+		t.Fatalf("late parameter must be mean of its snapshots 7 but %f", V.W[0])
+	}
+	swa.SwapOut(model)
+}
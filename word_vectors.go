@@ -0,0 +1,298 @@
+package gortex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type VectorFormat int
+
+const (
+	TextVectors    VectorFormat = iota // word2vec text, fastText .vec and GloVe, "count dim" header line is optional
+	Word2VecBinary                     // word2vec binary, header line then word, space and little endian float32 vector
+)
+
+// MissingInit is initialization of lookup table columns for tokens without pretrained vector
+type MissingInit int
+
+const (
+	MissingRandom MissingInit = iota // normal distribution with mean and deviation of pretrained vectors
+	MissingZero
+	MissingMean // mean of pretrained vectors
+)
+
+type VectorsOp struct {
+	Format    VectorFormat
+	Missing   MissingInit
+	Lowercase bool // use vector of lowercased token when exact one is not found
+	Limit     int  // read only Limit first vectors of file, 0 reads all
+}
+
+// Coverage reports how many dictionary tokens got pretrained vectors
+type Coverage struct {
+	Found             int
+	Missing           int
+	Lowercased        int      // found by lowercased token
+	FrequencyCoverage float32  // fraction of token occurrences covered by pretrained vectors
+	MissingTokens     []string // most frequent first
+}
+
+func (c *Coverage) String() string {
+	total := c.Found + c.Missing
+	return fmt.Sprintf("pretrained vectors cover %d of %d tokens (%.2f%%, %d lowercased), %.2f%% of occurrences",
+		c.Found, total, 100*float32(c.Found)/float32(MaxInt(total, 1)), c.Lowercased, 100*c.FrequencyCoverage)
+}
+
+// maxVectorDimension bounds dimension read from header, known pretrained vectors are far below it
+const maxVectorDimension = 1 << 16
+
+// readWordVectors calls visit for every vector of file, returns dimension of vectors
+func readWordVectors(r io.Reader, op VectorsOp, visit func(word string, vector []float32)) (dim int, e error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+	line, e := br.ReadString('\n')
+	if e != nil && (e != io.EOF || len(line) == 0) {
+		return 0, e
+	}
+	fields := strings.Fields(line)
+	header := false
+	if len(fields) == 2 {
+		_, e1 := strconv.Atoi(fields[0])
+		d, e2 := strconv.Atoi(fields[1])
+		if e1 == nil && e2 == nil {
+			header = true
+			dim = d
+		}
+	}
+	if op.Format == Word2VecBinary && !header {
+		return 0, fmt.Errorf("word2vec binary file must start with \"count dim\" header but %q found", strings.TrimSpace(line))
+	}
+	if !header {
+		dim = len(fields) - 1
+	}
+	if dim < 1 {
+		return 0, fmt.Errorf("can not figure out vector dimension from %q", strings.TrimSpace(line))
+	}
+	if dim > maxVectorDimension {
+		return 0, fmt.Errorf("vector dimension %d of %q is above %d", dim, strings.TrimSpace(line), maxVectorDimension)
+	}
+	if op.Format == Word2VecBinary {
+		return dim, readBinaryVectors(br, dim, op.Limit, visit)
+	}
+	count := 0
+	for {
+		if !header && len(fields) > 0 {
+			if len(fields) < dim+1 {
+				return 0, fmt.Errorf("line %d has %d values but %d expected", count+1, len(fields)-1, dim)
+			}
+			// some GloVe tokens contain spaces, vector is always at the end
+			split := len(fields) - dim
+			vector := make([]float32, dim)
+			for i, f := range fields[split:] {
+				v, e := strconv.ParseFloat(f, 32)
+				if e != nil {
+					return 0, fmt.Errorf("vector of %s: %v", fields[0], e)
+				}
+				vector[i] = float32(v)
+			}
+			visit(strings.Join(fields[:split], " "), vector)
+			count++
+			if op.Limit > 0 && count >= op.Limit {
+				return dim, nil
+			}
+		}
+		header = false
+		if e == io.EOF {
+			return dim, nil
+		}
+		if line, e = br.ReadString('\n'); e != nil && e != io.EOF {
+			return 0, e
+		}
+		fields = strings.Fields(line)
+	}
+}
+
+func readBinaryVectors(br *bufio.Reader, dim, limit int, visit func(word string, vector []float32)) error {
+	b := make([]byte, 4*dim)
+	for count := 0; limit == 0 || count < limit; count++ {
+		word, e := br.ReadString(' ')
+		if e == io.EOF && len(strings.TrimSpace(word)) == 0 {
+			return nil
+		}
+		if e != nil {
+			return e
+		}
+		word = strings.TrimSpace(word)
+		if _, e = io.ReadFull(br, b); e != nil {
+			return fmt.Errorf("vector of %s: %v", word, e)
+		}
+		vector := make([]float32, dim)
+		for i := range vector {
+			vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+		}
+		visit(word, vector)
+	}
+	return nil
+}
+
+func openWordVectors(name string, op VectorsOp, visit func(word string, vector []float32)) (int, error) {
+	f, e := os.Open(name)
+	if e != nil {
+		return 0, e
+	}
+	defer f.Close()
+	return readWordVectors(f, op, visit)
+}
+
+// fillMissing initializes columns of lookup table which have no pretrained vector
+func fillMissing(lt *Matrix, found []bool, missing MissingInit) {
+	mean := make([]float32, lt.Rows)
+	var sum, sum2 float64
+	n := 0
+	for id, ok := range found {
+		if ok {
+			column := lt.W[id*lt.Rows : (id+1)*lt.Rows]
+			for i, v := range column {
+				mean[i] += v
+				sum += float64(v)
+				sum2 += float64(v) * float64(v)
+			}
+			n++
+		}
+	}
+	if n > 0 {
+		for i := range mean {
+			mean[i] /= float32(n)
+		}
+	}
+	total := float64(MaxInt(n*lt.Rows, 1))
+	mu := sum / total
+	dev := math.Sqrt(math.Max(sum2/total-mu*mu, 0))
+	if n == 0 {
+		dev = 1
+	}
+	for id, ok := range found {
+		if ok {
+			continue
+		}
+		column := lt.W[id*lt.Rows : (id+1)*lt.Rows]
+		for i := range column {
+			switch missing {
+			case MissingZero:
+				column[i] = 0
+			case MissingMean:
+				column[i] = mean[i]
+			default:
+				column[i] = float32(rand.NormFloat64()*dev + mu)
+			}
+		}
+	}
+}
+
+// LoadWordVectors makes lookup table of dic.Len() columns filled by pretrained vectors of dictionary tokens
+func LoadWordVectors(name string, dic *Dictionary, op VectorsOp) (*Matrix, *Coverage, error) {
+	lowercased := make(map[string][]uint)
+	if op.Lowercase {
+		for token, id := range dic.Token2ID {
+			lower := strings.ToLower(token)
+			lowercased[lower] = append(lowercased[lower], id)
+		}
+	}
+	var lt *Matrix
+	found := make([]bool, dic.Len())
+	exact := make([]bool, dic.Len())
+	set := func(id uint, vector []float32) {
+		copy(lt.W[int(id)*lt.Rows:], vector)
+		found[id] = true
+	}
+	_, e := openWordVectors(name, op, func(word string, vector []float32) {
+		if lt == nil {
+			lt = Mat(len(vector), dic.Len())
+		}
+		if id, ok := dic.Token2ID[word]; ok && !exact[id] {
+			set(id, vector)
+			exact[id] = true
+		}
+		// files are sorted by frequency so first lowercased match is kept
+		for _, id := range lowercased[word] {
+			if !found[id] {
+				set(id, vector)
+			}
+		}
+	})
+	if e != nil {
+		return nil, nil, e
+	}
+	if lt == nil {
+		return nil, nil, fmt.Errorf("no vectors found in %s", name)
+	}
+	fillMissing(lt, found, op.Missing)
+
+	coverage := new(Coverage)
+	var covered, occurrences float64
+	for token, id := range dic.Token2ID {
+		frequency := float64(dic.Token2Frequency[token])
+		occurrences += frequency
+		if found[id] {
+			coverage.Found++
+			covered += frequency
+			if !exact[id] {
+				coverage.Lowercased++
+			}
+		} else {
+			coverage.Missing++
+			coverage.MissingTokens = append(coverage.MissingTokens, token)
+		}
+	}
+	if occurrences > 0 {
+		coverage.FrequencyCoverage = float32(covered / occurrences)
+	}
+	sort.Slice(coverage.MissingTokens, func(i, j int) bool {
+		a, b := coverage.MissingTokens[i], coverage.MissingTokens[j]
+		if dic.Token2Frequency[a] != dic.Token2Frequency[b] {
+			return dic.Token2Frequency[a] > dic.Token2Frequency[b]
+		}
+		return a < b
+	})
+	return lt, coverage, nil
+}
+
+// DictionaryFromWordVectors builds dictionary of vector file tokens and its lookup table,
+// token frequencies follow file order so Dictionary.Top keeps first vectors,
+// special tokens get vectors according to op.Missing
+func DictionaryFromWordVectors(name string, op VectorsOp) (*Dictionary, *Matrix, error) {
+	dic := NewDictionary()
+	var words []string
+	var vectors [][]float32
+	_, e := openWordVectors(name, op, func(word string, vector []float32) {
+		if _, ok := dic.Token2ID[word]; ok {
+			return
+		}
+		dic.Add(word)
+		words = append(words, word)
+		vectors = append(vectors, vector)
+	})
+	if e != nil {
+		return nil, nil, e
+	}
+	if len(vectors) == 0 {
+		return nil, nil, fmt.Errorf("no vectors found in %s", name)
+	}
+	lt := Mat(len(vectors[0]), dic.Len())
+	found := make([]bool, dic.Len())
+	for rank, word := range words {
+		id := dic.Token2ID[word]
+		dic.Token2Frequency[word] = uint(len(words) - rank)
+		copy(lt.W[int(id)*lt.Rows:], vectors[rank])
+		found[id] = true
+	}
+	fillMissing(lt, found, op.Missing)
+	return dic, lt, nil
+}
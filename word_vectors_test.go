@@ -0,0 +1,75 @@
+package gortex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWordVectors(t *testing.T) {
+	dir := t.TempDir()
+	text := "3 2\nthe 1 2\nhello 3 4\nyork 5 6\n"
+	glove := "the 1 2\nhello 3 4\nyork 5 6\n"
+	var bin bytes.Buffer
+	bin.WriteString("3 2\n")
+	for _, v := range []struct {
+		word   string
+		vector []float32
+	}{{"the", []float32{1, 2}}, {"hello", []float32{3, 4}}, {"york", []float32{5, 6}}} {
+		bin.WriteString(v.word + " ")
+		for _, f := range v.vector {
+			binary.Write(&bin, binary.LittleEndian, math.Float32bits(f))
+		}
+		bin.WriteString("\n")
+	}
+	files := map[string]VectorFormat{"text.vec": TextVectors, "glove.txt": TextVectors, "w2v.bin": Word2VecBinary}
+	os.WriteFile(filepath.Join(dir, "text.vec"), []byte(text), 0644)
+	os.WriteFile(filepath.Join(dir, "glove.txt"), []byte(glove), 0644)
+	os.WriteFile(filepath.Join(dir, "w2v.bin"), bin.Bytes(), 0644)
+
+	dic := NewDictionary()
+	for _, token := range []string{"Hello", "the", "the", "unseen"} {
+		dic.Add(token)
+	}
+	for file, format := range files {
+		name := filepath.Join(dir, file)
+		lt, coverage, e := LoadWordVectors(name, dic, VectorsOp{Format: format, Missing: MissingMean, Lowercase: true})
+		if e != nil {
+			t.Fatalf("%s: %v", file, e)
+		}
+		if lt.Rows != 2 || lt.Columns != dic.Len() {
+			t.Fatalf("%s: lookup table is %dx%d", file, lt.Rows, lt.Columns)
+		}
+		hello := dic.IDByToken("Hello")
+		if lt.W[hello*2] != 3 || lt.W[hello*2+1] != 4 {
+			t.Fatalf("%s: lowercased vector is not used %v", file, lt.W[hello*2:hello*2+2])
+		}
+		unseen := dic.IDByToken("unseen")
+		if lt.W[unseen*2] != 2 || lt.W[unseen*2+1] != 3 {
+			t.Fatalf("%s: missing token must get mean vector but %v found", file, lt.W[unseen*2:unseen*2+2])
+		}
+		if coverage.Found != 2 || coverage.Lowercased != 1 || coverage.Missing != dic.Len()-2 {
+			t.Fatalf("%s: wrong coverage %+v", file, coverage)
+		}
+
+		vdic, vlt, e := DictionaryFromWordVectors(name, VectorsOp{Format: format, Missing: MissingZero})
+		if e != nil {
+			t.Fatalf("%s: %v", file, e)
+		}
+		id := vdic.IDByToken("york")
+		if vdic.Len() != 6 || vlt.W[id*2] != 5 || vlt.W[int(vdic.IDByToken(UNK))*2] != 0 {
+			t.Fatalf("%s: wrong dictionary built from vectors", file)
+		}
+		if top := vdic.Top(4); top.Len() != 4 {
+			t.Fatalf("%s: Top(4) has %d tokens", file, top.Len())
+		}
+	}
+	for _, header := range []string{"3 -5\n", "3 0\n", "3 1000000000\n"} {
+		if _, e := readWordVectors(bytes.NewBufferString(header+"the "), VectorsOp{Format: Word2VecBinary}, func(string, []float32) {}); e == nil {
+			t.Fatalf("wrong dimension in header %q must be reported", header)
+		}
+	}
+}